@@ -22,15 +22,14 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
-	"image/png"
 	"log"
 	"math"
 	"os"
-	"os/exec"
 	"strings"
 
 	"github.com/golang/freetype"
 	"github.com/golang/freetype/truetype"
+	"github.com/nigeltao/nigeltao.github.io/lib/animgif"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/gofont/goitalic"
 	"golang.org/x/image/font/gofont/gomono"
@@ -80,24 +79,17 @@ func main() {
 
 	// jsonptr-buffers.gif
 	if true {
-		cmdArgs := []string{"-delay", "25"}
+		a := &animgif.Animation{}
+		delay := 25
 		for frame := 0; frame < 25; frame++ {
-			filename := fmt.Sprintf("_temp-%03d.png", frame)
-			out, err := os.Create(filename)
-			if err != nil {
-				log.Fatal(err)
+			if frame == 24 {
+				delay = 200
 			}
-			if err := png.Encode(out, doBuffers(frame)); err != nil {
+			if err := a.Add(doBuffers(frame), delay); err != nil {
 				log.Fatal(err)
 			}
-			out.Close()
-
-			if frame == 24 {
-				cmdArgs = append(cmdArgs, "-delay", "200")
-			}
-			cmdArgs = append(cmdArgs, filename)
 		}
-		encode(cmdArgs, "jsonptr-buffers.gif")
+		encode(a, "jsonptr-buffers.gif")
 	}
 
 	// jsonptr-readers-writers-compactions.gif
@@ -113,7 +105,8 @@ func main() {
 
 		frame := 0
 		compacted := false
-		cmdArgs := []string{"-delay", "50"}
+		a := &animgif.Animation{}
+		delay := 50
 		for s := rhyme0; s != ""; frame++ {
 			if s[0] == '_' {
 				top0 = "Drain, rn=1, filler"
@@ -154,22 +147,14 @@ func main() {
 				}
 			}
 
-			filename := fmt.Sprintf("_temp-%03d.png", frame)
-			out, err := os.Create(filename)
-			if err != nil {
-				log.Fatal(err)
+			if frame == 22 {
+				delay = 200
 			}
-			if err := png.Encode(out, doRWC(s, pos, wi, ri, top0, top1, closed)); err != nil {
+			if err := a.Add(doRWC(s, pos, wi, ri, top0, top1, closed), delay); err != nil {
 				log.Fatal(err)
 			}
-			out.Close()
-
-			if frame == 22 {
-				cmdArgs = append(cmdArgs, "-delay", "200")
-			}
-			cmdArgs = append(cmdArgs, filename)
 		}
-		encode(cmdArgs, "jsonptr-readers-writers-compactions.gif")
+		encode(a, "jsonptr-readers-writers-compactions.gif")
 	}
 
 	// jsonptr-csp.gif
@@ -183,7 +168,8 @@ func main() {
 		}
 
 		const width = charWidth * 16
-		cmdArgs := []string{"-delay", "5"}
+		a := &animgif.Animation{}
+		delay := 5
 
 		stages := []struct {
 			step int
@@ -219,7 +205,6 @@ func main() {
 		}
 
 		buffers := [3]wiRi{}
-		frame := 0
 		for i, v := range stages {
 			jMax := N
 			prev := buffers
@@ -239,40 +224,27 @@ func main() {
 					buffers[v.buf1].ri = ((c0 * prev[v.buf1].ri) + c1*v.ri1*14) / C
 				}
 
-				filename := fmt.Sprintf("_temp-%03d.png", frame)
-				out, err := os.Create(filename)
-				if err != nil {
-					log.Fatal(err)
+				if (i == (len(stages) - 1)) && (j == (jMax - 1)) {
+					delay = 200
 				}
-				if err := png.Encode(out, doCSP(v.step, buffers)); err != nil {
+				if err := a.Add(doCSP(v.step, buffers), delay); err != nil {
 					log.Fatal(err)
 				}
-				out.Close()
-
-				if (i == (len(stages) - 1)) && (j == (jMax - 1)) {
-					cmdArgs = append(cmdArgs, "-delay", "200")
-				}
-				cmdArgs = append(cmdArgs, filename)
-				frame++
 			}
 		}
-		encode(cmdArgs, "jsonptr-csp.gif")
+		encode(a, "jsonptr-csp.gif")
 	}
 }
 
-func encode(cmdArgs []string, filename string) {
-	cmdArgs = append(cmdArgs, "_temp.gif")
-	if err := exec.Command("convert", cmdArgs...).Run(); err != nil {
+func encode(a *animgif.Animation, filename string) {
+	out, err := os.Create(filename)
+	if err != nil {
 		log.Fatal(err)
 	}
-
-	optArgs := []string{
-		"-O3",
-		"_temp.gif",
-		"-o",
-		filename,
+	if err := a.Encode(out); err != nil {
+		log.Fatal(err)
 	}
-	if err := exec.Command("gifsicle", optArgs...).Run(); err != nil {
+	if err := out.Close(); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -23,13 +23,12 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
-	"image/png"
 	"log"
 	"os"
-	"os/exec"
 
 	"github.com/golang/freetype"
 	"github.com/golang/freetype/truetype"
+	"github.com/nigeltao/nigeltao.github.io/lib/animgif"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/gofont/gomono"
 )
@@ -62,20 +61,13 @@ func mainAnimation() {
 		theFont = f
 	}
 
-	cmdArgs := []string{"-delay", "20"}
+	a := &animgif.Animation{}
 	for frame := 0; frame < 10*nSteps; frame++ {
-		filename := fmt.Sprintf("_temp-%03d.png", frame)
-		out, err := os.Create(filename)
-		if err != nil {
-			log.Fatal(err)
-		}
-		if err := png.Encode(out, anim(frame)); err != nil {
+		if err := a.Add(anim(frame), 20); err != nil {
 			log.Fatal(err)
 		}
-		out.Close()
-		cmdArgs = append(cmdArgs, filename)
 	}
-	encode(cmdArgs, "parse-number-f64-simple.gif")
+	encode(a, "parse-number-f64-simple.gif")
 }
 
 func anim(frame int) draw.Image {
@@ -288,19 +280,15 @@ func quantize(pix []byte) {
 	}
 }
 
-func encode(cmdArgs []string, filename string) {
-	cmdArgs = append(cmdArgs, "_temp.gif")
-	if err := exec.Command("convert", cmdArgs...).Run(); err != nil {
+func encode(a *animgif.Animation, filename string) {
+	out, err := os.Create(filename)
+	if err != nil {
 		log.Fatal(err)
 	}
-
-	optArgs := []string{
-		"-O3",
-		"_temp.gif",
-		"-o",
-		filename,
+	if err := a.Encode(out); err != nil {
+		log.Fatal(err)
 	}
-	if err := exec.Command("gifsicle", optArgs...).Run(); err != nil {
+	if err := out.Close(); err != nil {
 		log.Fatal(err)
 	}
 }
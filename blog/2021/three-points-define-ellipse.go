@@ -16,11 +16,6 @@
 
 // three-points-define-ellipse.go creates the images for the "Three Points
 // Define an Ellipse" blog post.
-//
-// The final steps:
-//
-// convert -delay 100 three-points-define-ellipse-*.png _temp.gif
-// gifsicle -O3 _temp.gif -o three-points-define-ellipse.gif
 package main
 
 import (
@@ -33,6 +28,7 @@ import (
 	"strconv"
 
 	"github.com/golang/freetype"
+	"github.com/nigeltao/nigeltao.github.io/lib/animgif"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/gofont/gomono"
 )
@@ -106,15 +102,31 @@ func main() {
 	radii[3][1] = -radii[1][1]
 	radii[4] = radii[0]
 
+	a := &animgif.Animation{}
 	for step := 0; step < 9; step++ {
+		m := do(step)
 		out, err := os.Create("three-points-define-ellipse-" + strconv.Itoa(step) + ".png")
 		if err != nil {
 			log.Fatal(err)
 		}
-		if err := png.Encode(out, do(step)); err != nil {
+		if err := png.Encode(out, m); err != nil {
 			log.Fatal(err)
 		}
 		out.Close()
+		if err := a.Add(m, 100); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	out, err := os.Create("three-points-define-ellipse.gif")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := a.Encode(out); err != nil {
+		log.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		log.Fatal(err)
 	}
 }
 
@@ -27,6 +27,7 @@ import (
 	"log"
 	"os"
 
+	"github.com/nigeltao/nigeltao.github.io/lib/animgif"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/gofont/goregular"
 	"golang.org/x/image/font/inconsolata"
@@ -35,11 +36,14 @@ import (
 )
 
 func main() {
+	a := &animgif.Animation{}
 	for i := 0; i < 3; i++ {
-		visualize1DFilter(i)
+		// 100 delay units is 1 second.
+		if err := a.Add(visualize1DFilter(i), 100); err != nil {
+			log.Fatal(err)
+		}
 	}
-	// Afterwards, run this (100 delay units is 1 second):
-	// convert -delay 100 jpeg-chroma-upsampling.1d-filter-?.png jpeg-chroma-upsampling.1d-filter.gif
+	writeGIF(a, "jpeg-chroma-upsampling.1d-filter.gif")
 
 	magnify16x("at-mouquins.128x128.q90.box-filter")
 	magnify16x("at-mouquins.128x128.q90.triangle-filter")
@@ -62,7 +66,7 @@ func main() {
 	)
 }
 
-func visualize1DFilter(phase int) {
+func visualize1DFilter(phase int) *image.RGBA {
 	in := [8]int{
 		0x60, 0x20, 0x80, 0x30, 0x10, 0x80, 0x50, 0x30,
 	}
@@ -231,6 +235,19 @@ func visualize1DFilter(phase int) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	return out
+}
+
+func writeGIF(a *animgif.Animation, filename string) {
+	outFile, err := os.Create(filename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer outFile.Close()
+	err = a.Encode(outFile)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 func magnify16x(basename string) {
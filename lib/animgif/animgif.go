@@ -0,0 +1,348 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package animgif assembles animated GIFs from a sequence of frames, such as
+// those drawn by the programs that generate the blog posts' images. It does,
+// in-process, what "convert -delay etc *.png _temp.gif" (ImageMagick) followed
+// by "gifsicle -O3 _temp.gif" does:
+//
+//   - The global palette is built from every frame's colors. It is exact when
+//     there are at most 255 colors (leaving room for a transparent entry) and
+//     otherwise uses median cut quantization. Colors that are changed by
+//     frames that change few colors come first, so that those frames can use
+//     narrower LZW literals.
+//   - Each frame after the first is cropped to the rectangle that changed
+//     since the previous frame and is drawn over it (with no disposal).
+//   - Within that rectangle, unchanged pixels can be transparent. Each frame
+//     can also have a smaller, local palette of just the colors that changed.
+//     Whatever makes for the smallest file is chosen, frame by frame.
+//   - A frame that is identical to the previous one is dropped, adding its
+//     delay to the previous frame's.
+//
+// The result is typically smaller than gifsicle's, partly because the GIF is
+// written by this package's own encoder rather than by image/gif's. See
+// encode for details.
+package animgif
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"sort"
+)
+
+// Animation is a sequence of frames. The zero value is an empty animation,
+// ready to use.
+//
+// Frames are held in memory (cropped, as each is added, to what changed since
+// the previous frame) until Encode, as the palette depends on all of them.
+type Animation struct {
+	// bounds are the first frame's bounds, translated to the origin.
+	bounds image.Rectangle
+
+	// prev is the most recently added frame, in full.
+	prev *image.RGBA
+
+	frames    []frame
+	histogram map[color.RGBA]histogramEntry
+}
+
+type frame struct {
+	// m is the part of the frame that differs from the previous frame, or the
+	// whole frame for the first frame. It is nil if nothing changed.
+	m     *image.RGBA
+	delay int
+
+	// colors are the distinct colors of the pixels that changed.
+	colors map[color.RGBA]bool
+}
+
+// Add appends a frame, shown for delay hundredths of a second. Every frame
+// must be fully opaque and the same size as the first.
+func (a *Animation) Add(m image.Image, delay int) error {
+	b := m.Bounds()
+	if a.prev == nil {
+		if b.Empty() {
+			return errors.New("animgif: empty frame")
+		}
+		a.bounds = b.Sub(b.Min)
+		a.histogram = map[color.RGBA]histogramEntry{}
+	} else if b.Size() != a.bounds.Size() {
+		return errors.New("animgif: frames have different sizes")
+	}
+	if delay < 0 {
+		return errors.New("animgif: negative delay")
+	}
+
+	rgba := image.NewRGBA(a.bounds)
+	draw.Draw(rgba, a.bounds, m, b.Min, draw.Src)
+	for i := 3; i < len(rgba.Pix); i += 4 {
+		if rgba.Pix[i] != 0xFF {
+			return errors.New("animgif: frame is not opaque")
+		}
+	}
+
+	r := a.bounds
+	if a.prev != nil {
+		r = changedRect(a.prev, rgba)
+	}
+	f := frame{delay: delay, colors: map[color.RGBA]bool{}}
+	if !r.Empty() {
+		f.m = image.NewRGBA(r)
+		draw.Draw(f.m, r, rgba, r.Min, draw.Src)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				i := rgba.PixOffset(x, y)
+				c := color.RGBA{rgba.Pix[i+0], rgba.Pix[i+1], rgba.Pix[i+2], rgba.Pix[i+3]}
+				a.histogram[c] = histogramEntry{c: c, n: a.histogram[c].n + 1}
+				if (a.prev == nil) || !bytes.Equal(a.prev.Pix[i:i+4], rgba.Pix[i:i+4]) {
+					f.colors[c] = true
+				}
+			}
+		}
+	}
+	a.frames = append(a.frames, f)
+	a.prev = rgba
+	return nil
+}
+
+// changedRect returns the smallest rectangle that contains every pixel that
+// differs between two images with the same bounds.
+func changedRect(m0 *image.RGBA, m1 *image.RGBA) image.Rectangle {
+	b := m0.Rect
+	r := image.Rectangle{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := m0.PixOffset(b.Min.X, y)
+		j := m0.PixOffset(b.Max.X, y)
+		row0, row1 := m0.Pix[i:j], m1.Pix[i:j]
+		if bytes.Equal(row0, row1) {
+			continue
+		}
+		x0, x1 := 0, len(row0)/4
+		for (x0 < x1) && bytes.Equal(row0[4*x0:4*x0+4], row1[4*x0:4*x0+4]) {
+			x0++
+		}
+		for (x0 < x1) && bytes.Equal(row0[4*x1-4:4*x1], row1[4*x1-4:4*x1]) {
+			x1--
+		}
+		r = r.Union(image.Rect(b.Min.X+x0, y, b.Min.X+x1, y+1))
+	}
+	return r
+}
+
+// Encode writes the animation as a GIF that loops forever.
+func (a *Animation) Encode(w io.Writer) error {
+	if len(a.frames) == 0 {
+		return errors.New("animgif: no frames")
+	}
+	// Visit the frames in order of how many colors they change, fewest first.
+	// Each color's rank is the first such frame that changes it.
+	order := make([]int, len(a.frames))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(a.frames[order[i]].colors) < len(a.frames[order[j]].colors)
+	})
+	ranked := map[color.RGBA]bool{}
+	for rank, i := range order {
+		for c := range a.frames[i].colors {
+			if !ranked[c] {
+				ranked[c] = true
+				e := a.histogram[c]
+				e.rank = rank
+				a.histogram[c] = e
+			}
+		}
+	}
+
+	// The global palette ends with a transparent entry, which frames can use
+	// if they use every other index.
+	palette, indexes := buildPalette(a.histogram, 255)
+	palette = append(palette, color.RGBA{})
+
+	g := &gif.GIF{
+		Config: image.Config{
+			ColorModel: palette,
+			Width:      a.bounds.Dx(),
+			Height:     a.bounds.Dy(),
+		},
+	}
+	// canvas is what a viewer shows after each frame is drawn.
+	canvas := image.NewPaletted(a.bounds, palette)
+	for i, f := range a.frames {
+		if f.m == nil {
+			g.Delay[len(g.Delay)-1] += f.delay
+			continue
+		}
+
+		// Map the frame to the palette and find what changed on the canvas,
+		// which can be less than what changed in the frame if the palette is
+		// quantized.
+		p := image.NewPaletted(f.m.Rect, palette)
+		r := image.Rectangle{}
+		for y := f.m.Rect.Min.Y; y < f.m.Rect.Max.Y; y++ {
+			for x := f.m.Rect.Min.X; x < f.m.Rect.Max.X; x++ {
+				k := f.m.PixOffset(x, y)
+				c := color.RGBA{f.m.Pix[k+0], f.m.Pix[k+1], f.m.Pix[k+2], f.m.Pix[k+3]}
+				index := indexes[c]
+				p.SetColorIndex(x, y, index)
+				if (i == 0) || (canvas.ColorIndexAt(x, y) != index) {
+					r = r.Union(image.Rect(x, y, x+1, y+1))
+				}
+			}
+		}
+		if r.Empty() {
+			g.Delay[len(g.Delay)-1] += f.delay
+			continue
+		}
+		p = p.SubImage(r).(*image.Paletted)
+
+		q := p
+		if i > 0 {
+			q = optimize(p, canvas)
+		}
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			copy(canvas.Pix[canvas.PixOffset(r.Min.X, y):], p.Pix[p.PixOffset(r.Min.X, y):p.PixOffset(r.Max.X, y)])
+		}
+
+		g.Image = append(g.Image, q)
+		g.Delay = append(g.Delay, f.delay)
+		g.Disposal = append(g.Disposal, gif.DisposalNone)
+	}
+	return encode(w, g)
+}
+
+// optimize returns whichever encoding of the frame p (which uses the global
+// palette and is drawn over the canvas) is smallest:
+//
+//   - p as is.
+//   - p using the global palette but with a transparent index for the pixels
+//     that are the same as on the canvas. The transparent index is the first
+//     one that the other pixels don't use.
+//   - p using a local palette of just the colors of the pixels that aren't
+//     the same as on the canvas, plus a transparent index for those that are.
+func optimize(p *image.Paletted, canvas *image.Paletted) *image.Paletted {
+	global := p.Palette
+	best, bestSize := p, frameSize(p, global)
+
+	pix := pixels(p)
+	same := make([]bool, len(pix))
+	used := [256]bool{}
+	for i, y := 0, p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x, i = x+1, i+1 {
+			same[i] = pix[i] == canvas.ColorIndexAt(x, y)
+			used[pix[i]] = used[pix[i]] || !same[i]
+		}
+	}
+
+	// The global palette, with a transparent index. There's always an unused
+	// index, as the global palette ends with a transparent entry.
+	t := 0
+	for used[t] {
+		t++
+	}
+	{
+		palette := append(color.Palette(nil), global...)
+		palette[t] = color.RGBA{}
+		options := make([][2]uint8, len(pix))
+		for i, index := range pix {
+			options[i] = [2]uint8{index, index}
+			if same[i] {
+				options[i][0] = uint8(t)
+			}
+		}
+		q := choose(p.Rect, palette, options)
+		if size := frameSize(q, global); bestSize > size {
+			best, bestSize = q, size
+		}
+	}
+
+	// A local palette, with a transparent index. local maps global palette
+	// indexes to one plus the local palette index, or zero for none.
+	local, numLocal := [256]int{}, 0
+	for i, index := range pix {
+		if !same[i] && (local[index] == 0) {
+			numLocal++
+			local[index] = numLocal
+		}
+	}
+	if numLocal < 256 {
+		palette := make(color.Palette, numLocal+1)
+		for i, l := range local {
+			if l > 0 {
+				palette[l-1] = global[i]
+			}
+		}
+		t := uint8(numLocal)
+		palette[t] = color.RGBA{}
+		options := make([][2]uint8, len(pix))
+		for i, index := range pix {
+			l := t
+			if local[index] > 0 {
+				l = uint8(local[index] - 1)
+			}
+			options[i] = [2]uint8{l, l}
+			if same[i] {
+				options[i][0] = t
+			}
+		}
+		q := choose(p.Rect, palette, options)
+		if size := frameSize(q, global); bestSize > size {
+			best, bestSize = q, size
+		}
+	}
+	return best
+}
+
+// pixels returns p's palette indexes, row by row.
+func pixels(p *image.Paletted) []uint8 {
+	if p.Stride == p.Rect.Dx() {
+		return p.Pix[:p.Rect.Dx()*p.Rect.Dy()]
+	}
+	ret := make([]uint8, 0, p.Rect.Dx()*p.Rect.Dy())
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		ret = append(ret, p.Pix[p.PixOffset(p.Rect.Min.X, y):p.PixOffset(p.Rect.Max.X, y)]...)
+	}
+	return ret
+}
+
+// litWidth returns the LZW literal width for n palette indexes, the same way
+// that image/gif does.
+func litWidth(n int) int {
+	w := 2
+	for (1 << w) < n {
+		w++
+	}
+	return w
+}
+
+// frameSize estimates the size of a frame in a GIF file: its LZW-compressed
+// pixels and, if it needs one, its local color table.
+func frameSize(p *image.Paletted, global color.Palette) int {
+	n := lzwSize(pixels(p))
+	if !isGlobal(p.Palette, global) {
+		n += tableSize(len(p.Palette))
+	}
+	return n
+}
+
+// lzwSize returns the size of the palette indexes pix, LZW-compressed.
+func lzwSize(pix []uint8) int {
+	return len(compress(pix))
+}
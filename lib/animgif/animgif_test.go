@@ -0,0 +1,263 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package animgif
+
+import (
+	"bytes"
+	"compress/lzw"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+)
+
+var (
+	black  = color.RGBA{0x00, 0x00, 0x00, 0xFF}
+	white  = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	red    = color.RGBA{0xFF, 0x00, 0x00, 0xFF}
+	green  = color.RGBA{0x00, 0xFF, 0x00, 0xFF}
+	blue   = color.RGBA{0x00, 0x00, 0xFF, 0xFF}
+	yellow = color.RGBA{0xFF, 0xFF, 0x00, 0xFF}
+)
+
+// testFrames returns a small animation's frames and their delays. Frames 2
+// and 3 are the same, so they should be encoded as one frame.
+func testFrames() (frames []*image.RGBA, delays []int) {
+	r := image.Rect(0, 0, 64, 48)
+	m := image.NewRGBA(r)
+	draw.Draw(m, r, image.NewUniform(white), image.Point{}, draw.Src)
+
+	add := func(c color.Color, rect image.Rectangle, delay int) {
+		draw.Draw(m, rect, image.NewUniform(c), image.Point{}, draw.Src)
+		clone := image.NewRGBA(r)
+		copy(clone.Pix, m.Pix)
+		frames = append(frames, clone)
+		delays = append(delays, delay)
+	}
+	add(black, image.Rect(4, 4, 20, 12), 100)
+	add(red, image.Rect(10, 8, 40, 30), 10)
+	add(green, image.Rect(30, 20, 60, 44), 20)
+	add(green, image.Rect(30, 20, 60, 44), 30)
+	add(blue, image.Rect(31, 21, 32, 22), 40)
+	add(yellow, image.Rect(0, 0, 64, 48), 50)
+	for i := 0; i < 24; i += 3 {
+		add(black, image.Rect(i, i, i+1, 48-i), 5)
+	}
+	return frames, delays
+}
+
+// composite returns what a viewer shows after each of g's frames is drawn,
+// checking that every frame is drawn over the previous one (with no
+// disposal).
+func composite(t *testing.T, g *gif.GIF) []*image.RGBA {
+	r := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	canvas := image.NewRGBA(r)
+	ret := []*image.RGBA(nil)
+	for i, p := range g.Image {
+		if g.Disposal[i] != gif.DisposalNone {
+			t.Fatalf("frame #%d: disposal: got %d, want DisposalNone", i, g.Disposal[i])
+		}
+		draw.Draw(canvas, p.Bounds(), p, p.Bounds().Min, draw.Over)
+		clone := image.NewRGBA(r)
+		copy(clone.Pix, canvas.Pix)
+		ret = append(ret, clone)
+	}
+	return ret
+}
+
+func encodeDecode(t *testing.T, frames []*image.RGBA, delays []int) (*gif.GIF, []byte) {
+	a := &Animation{}
+	for i, m := range frames {
+		if err := a.Add(m, delays[i]); err != nil {
+			t.Fatalf("Add #%d: %v", i, err)
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := a.Encode(buf); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	encoded := buf.Bytes()
+	g, err := gif.DecodeAll(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatalf("DecodeAll: %v", err)
+	}
+	return g, encoded
+}
+
+func TestRoundTrip(t *testing.T) {
+	frames, delays := testFrames()
+	g, _ := encodeDecode(t, frames, delays)
+
+	if (g.Config.Width != 64) || (g.Config.Height != 48) {
+		t.Fatalf("size: got %dx%d, want 64x48", g.Config.Width, g.Config.Height)
+	}
+	if g.LoopCount != 0 {
+		t.Errorf("LoopCount: got %d, want 0 (loop forever)", g.LoopCount)
+	}
+	if _, ok := g.Config.ColorModel.(color.Palette); !ok {
+		t.Fatalf("no global palette")
+	}
+
+	// Frames 2 and 3 are the same, so frame 3's delay is added to frame 2's.
+	wantFrames := append(append([]*image.RGBA(nil), frames[:3]...), frames[4:]...)
+	wantDelays := append([]int{delays[0], delays[1], delays[2] + delays[3]}, delays[4:]...)
+	if len(g.Image) != len(wantFrames) {
+		t.Fatalf("number of frames: got %d, want %d", len(g.Image), len(wantFrames))
+	}
+	for i, got := range composite(t, g) {
+		if !bytes.Equal(got.Pix, wantFrames[i].Pix) {
+			t.Errorf("frame #%d: pixels differ", i)
+		}
+		if g.Delay[i] != wantDelays[i] {
+			t.Errorf("frame #%d: delay: got %d, want %d", i, g.Delay[i], wantDelays[i])
+		}
+	}
+
+	// Only the first frame covers the whole image. Later ones are cropped to
+	// what changed. Single pixel changes can use a narrow local palette.
+	if b := g.Image[0].Bounds(); b != image.Rect(0, 0, 64, 48) {
+		t.Errorf("frame #0: bounds: got %v, want the whole image", b)
+	}
+	if b := g.Image[3].Bounds(); b != image.Rect(31, 21, 32, 22) {
+		t.Errorf("frame #3: bounds: got %v, want the one changed pixel", b)
+	}
+}
+
+func TestTransparency(t *testing.T) {
+	// Changing two opposite corners of a noisy image changes a frame-sized
+	// rectangle but only two of its pixels. Drawing the others as transparent
+	// compresses much better than repeating the noise.
+	rng := rand.New(rand.NewSource(1))
+	r := image.Rect(0, 0, 64, 64)
+	m0 := image.NewRGBA(r)
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			m0.SetRGBA(x, y, color.RGBA{uint8(16 * rng.Intn(16)), 0x40, 0x40, 0xFF})
+		}
+	}
+	m1 := image.NewRGBA(r)
+	copy(m1.Pix, m0.Pix)
+	m1.SetRGBA(1, 1, blue)
+	m1.SetRGBA(62, 62, blue)
+
+	g, _ := encodeDecode(t, []*image.RGBA{m0, m1}, []int{10, 20})
+	if len(g.Image) != 2 {
+		t.Fatalf("number of frames: got %d, want 2", len(g.Image))
+	}
+	if b := g.Image[1].Bounds(); b != image.Rect(1, 1, 63, 63) {
+		t.Errorf("frame #1: bounds: got %v, want (1,1)-(63,63)", b)
+	}
+	if transparentIndex(g.Image[1].Palette) < 0 {
+		t.Errorf("frame #1: got no transparent index, want one")
+	}
+	for i, got := range composite(t, g) {
+		if want := []*image.RGBA{m0, m1}[i]; !bytes.Equal(got.Pix, want.Pix) {
+			t.Errorf("frame #%d: pixels differ", i)
+		}
+	}
+}
+
+func TestManyColors(t *testing.T) {
+	// 32×32 distinct colors is more than fit in a GIF palette, so they are
+	// quantized.
+	r := image.Rect(0, 0, 32, 32)
+	m0 := image.NewRGBA(r)
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			m0.SetRGBA(x, y, color.RGBA{uint8(8 * x), uint8(8 * y), 0x80, 0xFF})
+		}
+	}
+	m1 := image.NewRGBA(r)
+	draw.Draw(m1, r, image.NewUniform(black), image.Point{}, draw.Src)
+
+	g, _ := encodeDecode(t, []*image.RGBA{m0, m1}, []int{10, 20})
+	if global := g.Config.ColorModel.(color.Palette); len(global) > 256 {
+		t.Fatalf("global palette: got %d colors, want at most 256", len(global))
+	}
+	got := composite(t, g)
+	if len(got) != 2 {
+		t.Fatalf("number of frames: got %d, want 2", len(got))
+	}
+	if !bytes.Equal(got[1].Pix, m1.Pix) {
+		t.Errorf("frame #1: pixels differ")
+	}
+	for i := 0; i < len(m0.Pix); i++ {
+		d := int(got[0].Pix[i]) - int(m0.Pix[i])
+		if (d < -32) || (32 < d) {
+			t.Fatalf("frame #0: pixel byte %d: got 0x%02X, want about 0x%02X", i, got[0].Pix[i], m0.Pix[i])
+		}
+	}
+}
+
+func TestAddErrors(t *testing.T) {
+	opaque := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(opaque, opaque.Bounds(), image.NewUniform(white), image.Point{}, draw.Src)
+
+	if err := (&Animation{}).Add(image.NewRGBA(image.Rect(0, 0, 4, 4)), 10); err == nil {
+		t.Errorf("translucent frame: got nil error")
+	}
+	if err := (&Animation{}).Add(opaque, -1); err == nil {
+		t.Errorf("negative delay: got nil error")
+	}
+	a := &Animation{}
+	if err := a.Add(opaque, 10); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := a.Add(image.NewRGBA(image.Rect(0, 0, 5, 4)), 10); err == nil {
+		t.Errorf("different size: got nil error")
+	}
+	if err := (&Animation{}).Encode(ioutil.Discard); err == nil {
+		t.Errorf("no frames: got nil error")
+	}
+}
+
+// TestLZW checks that the LZW encoder, with each of its clear policies, is
+// compatible with compress/lzw's GIF-style decoder, including for inputs that
+// fill the code table.
+func TestLZW(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	inputs := [][]uint8{nil, {0}, {3, 3, 3, 3}}
+	for _, n := range []int{2, 4, 16, 256} {
+		random := make([]uint8, 100000)
+		runs := make([]uint8, 100000)
+		for i := range random {
+			random[i] = uint8(rng.Intn(n))
+			runs[i] = uint8((i / (1 + rng.Intn(50))) % n)
+		}
+		inputs = append(inputs, random, runs)
+	}
+
+	e := &lzwEncoder{}
+	for i, pix := range inputs {
+		w := pixelWidth(pix)
+		for _, p := range clearPolicies {
+			e.encode(pix, uint(w), p)
+			r := lzw.NewReader(bytes.NewReader(e.out), lzw.LSB, w)
+			got, err := ioutil.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Errorf("input #%d, policy %v: %v", i, p, err)
+			} else if !bytes.Equal(got, pix) {
+				t.Errorf("input #%d, policy %v: round trip differs", i, p)
+			}
+		}
+		if got, want := len(compress(pix)), len(e.out); got > want {
+			t.Errorf("input #%d: compress: got %d bytes, want at most %d", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package animgif
+
+import (
+	"image"
+	"image/color"
+)
+
+// choose returns an image, using the given palette, that has one of two
+// options for each pixel (in row-by-row order), chosen to make its LZW
+// encoding short. For pixels that are the same as on the canvas, the options
+// are typically the transparent index or the pixel's own color index.
+//
+// One way to choose follows what an LZW encoder does, code by code (as if it
+// clears its code table as soon as it is full), but where the encoder
+// greedily extends each code by the next pixel, choose searches for the
+// choices that extend it the furthest. Other ways are simpler heuristics.
+// choose returns whichever is shortest.
+func choose(r image.Rectangle, palette color.Palette, options [][2]uint8) *image.Paletted {
+	// The literal width depends on the largest index chosen, which isn't
+	// known yet, so assume the largest option.
+	all := make([]uint8, 0, 2*len(options))
+	for _, o := range options {
+		all = append(all, o[0], o[1])
+	}
+	m := &matcher{
+		table:   map[uint32]uint32{},
+		clear:   uint32(1) << pixelWidth(all),
+		options: options,
+		chosen:  make([]uint8, len(options)),
+	}
+	m.hi = m.clear + 1
+
+	// Each iteration emits one code. first is the first pixel's literal.
+	first := m.bestStart(0)
+	for pos := 0; pos < len(m.options); {
+		m.chosen[pos] = first
+		code, n := m.longest(pos, uint32(first))
+		pos += n
+		if pos == len(m.options) {
+			break
+		}
+		first = m.bestStart(pos)
+		m.insert(code, first)
+	}
+
+	best := image.NewPaletted(r, palette)
+	copy(best.Pix, m.chosen)
+	bestSize := lzwSize(best.Pix)
+
+	// A run is a sequence of pixels that each have two (different) options.
+	for _, h := range [...]func(run [][2]uint8) int{
+		// Always choose the first option.
+		func(run [][2]uint8) int { return 0 },
+		// Choose the second option for a run that only has one color (in the
+		// second option), as gifsicle does, and the first option otherwise.
+		func(run [][2]uint8) int {
+			for _, o := range run {
+				if o[1] != run[0][1] {
+					return 0
+				}
+			}
+			return 1
+		},
+	} {
+		q := image.NewPaletted(r, palette)
+		for i := 0; i < len(options); {
+			j := i
+			for (j < len(options)) && (options[j][0] != options[j][1]) {
+				j++
+			}
+			if i == j {
+				q.Pix[i] = options[i][0]
+				i++
+				continue
+			}
+			k := h(options[i:j])
+			for ; i < j; i++ {
+				q.Pix[i] = options[i][k]
+			}
+		}
+		if size := lzwSize(q.Pix); bestSize > size {
+			best, bestSize = q, size
+		}
+	}
+	return best
+}
+
+// matcher holds the state for choose's search: the LZW code table and, for
+// each pixel, the options for and chosen palette index.
+type matcher struct {
+	table   map[uint32]uint32
+	clear   uint32
+	hi      uint32
+	options [][2]uint8
+	chosen  []uint8
+
+	// budget limits the search for the longest match, which is exponential in
+	// the worst case.
+	budget   int
+	best     []uint8
+	bestCode uint32
+	path     []uint8
+}
+
+// maxSearch is the search budget for each code.
+const maxSearch = 1024
+
+// bestStart returns the literal, out of the options for pixel pos, that
+// starts the longest match.
+func (m *matcher) bestStart(pos int) uint8 {
+	o := m.options[pos]
+	if o[0] == o[1] {
+		return o[0]
+	}
+	_, n0 := m.search(pos, uint32(o[0]))
+	_, n1 := m.search(pos, uint32(o[1]))
+	if (n1 > n0) || ((n1 == n0) && (pos > 0) && (m.chosen[pos-1] == o[1])) {
+		return o[1]
+	}
+	return o[0]
+}
+
+// longest finds the longest match starting at pixel pos (whose literal has
+// already been chosen) and records the choices made for the following pixels.
+// It returns the match's code and length.
+func (m *matcher) longest(pos int, literal uint32) (code uint32, n int) {
+	code, n = m.search(pos, literal)
+	copy(m.chosen[pos+1:], m.best)
+	return code, n
+}
+
+// search returns the code and length of the longest match starting at pixel
+// pos, leaving the choices made for the pixels after pos in m.best.
+func (m *matcher) search(pos int, literal uint32) (code uint32, n int) {
+	m.budget = maxSearch
+	m.best, m.bestCode = m.best[:0], literal
+	m.path = m.path[:0]
+	m.search1(pos+1, literal)
+	return m.bestCode, len(m.best) + 1
+}
+
+func (m *matcher) search1(pos int, code uint32) {
+	if pos == len(m.options) {
+		return
+	}
+	o := m.options[pos]
+	for i := range o {
+		if ((i == 1) && (o[0] == o[1])) || (m.budget <= 0) {
+			break
+		}
+		next, ok := m.table[code<<8|uint32(o[i])]
+		if !ok {
+			continue
+		}
+		m.budget--
+		m.path = append(m.path, o[i])
+		if len(m.best) < len(m.path) {
+			m.best, m.bestCode = append(m.best[:0], m.path...), next
+		}
+		m.search1(pos+1, next)
+		m.path = m.path[:len(m.path)-1]
+	}
+}
+
+// insert adds an entry to the LZW table, the same way that compress/lzw does.
+func (m *matcher) insert(code uint32, literal uint8) {
+	m.hi++
+	if m.hi == maxCode {
+		m.table = map[uint32]uint32{}
+		return
+	}
+	m.table[code<<8|uint32(literal)] = m.hi
+}
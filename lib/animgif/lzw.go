@@ -0,0 +1,198 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package animgif
+
+// compress/lzw, once its code table is full, always sends a clear code and
+// starts again with an empty table. But a full table often still compresses
+// well: GIF decoders (including image/gif) carry on using it until they see a
+// clear code. This LZW encoder only clears a full table once it stops
+// compressing well, per a clearPolicy. compress tries several policies.
+
+const (
+	maxCode = 1<<12 - 1
+
+	// The hash table has four times as many entries as there are codes, the
+	// same as compress/lzw. An entry is key<<12 | code, or zero for none.
+	lzwTableSize = 4 << 12
+	lzwTableMask = lzwTableSize - 1
+)
+
+// clearPolicy says when to clear a full code table: once the last window
+// codes sent have averaged more than bitsPerPixel bits per pixel. A zero
+// window means to clear as soon as the table is full, like compress/lzw.
+type clearPolicy struct {
+	window       int
+	bitsPerPixel float64
+}
+
+// clearPolicies are the policies that compress tries. Each one does best for
+// some of the blog posts' animations. Codes are at most 12 bits long, so the
+// last policy never clears.
+var clearPolicies = [...]clearPolicy{
+	{0, 0},
+	{32, 3},
+	{64, 1.25},
+	{64, 1},
+	{256, 1},
+	{1, 12},
+}
+
+// compress returns the LZW-compressed (in GIF's variant of LZW) palette
+// indexes pix, using whichever clear policy gives the shortest output. The
+// literal width is given by pixelWidth.
+func compress(pix []uint8) []byte {
+	e := &lzwEncoder{}
+	best := []byte(nil)
+	for _, p := range clearPolicies {
+		e.encode(pix, uint(pixelWidth(pix)), p)
+		if (best == nil) || (len(best) > len(e.out)) {
+			best = append(best[:0], e.out...)
+		}
+	}
+	return best
+}
+
+type lzwEncoder struct {
+	out   []byte
+	bits  uint32
+	nBits uint
+
+	litWidth uint
+	width    uint
+	hi       uint32
+	overflow uint32
+	full     bool
+	table    [lzwTableSize]uint32
+
+	// recent is a ring buffer holding, for the last window codes sent while
+	// the table is full, their widths in bits and lengths in pixels. numRecent
+	// counts those codes.
+	recent       [][2]int
+	numRecent    int
+	recentBits   int
+	recentPixels int
+}
+
+func (e *lzwEncoder) encode(pix []uint8, litWidth uint, p clearPolicy) {
+	e.out, e.bits, e.nBits = e.out[:0], 0, 0
+	e.litWidth = litWidth
+	e.reset()
+	e.write(1 << litWidth)
+	if len(pix) == 0 {
+		e.write(1<<litWidth + 1)
+		e.flush()
+		return
+	}
+
+	code, n := uint32(pix[0]), 1
+loop:
+	for _, x := range pix[1:] {
+		key := code<<8 | uint32(x)
+		hash := (key>>12 ^ key) & lzwTableMask
+		for h, t := hash, e.table[hash]; t != 0; {
+			if key == t>>12 {
+				code, n = t&maxCode, n+1
+				continue loop
+			}
+			h = (h + 1) & lzwTableMask
+			t = e.table[h]
+		}
+
+		e.write(code)
+		if e.full {
+			if e.shouldClear(n, p) {
+				e.write(1 << e.litWidth)
+				e.reset()
+			}
+		} else if e.incHi() {
+			if p.window == 0 {
+				e.write(1 << e.litWidth)
+				e.reset()
+			} else {
+				e.full = true
+			}
+		} else {
+			for e.table[hash] != 0 {
+				hash = (hash + 1) & lzwTableMask
+			}
+			e.table[hash] = key<<12 | e.hi
+		}
+		code, n = uint32(x), 1
+	}
+
+	e.write(code)
+	if !e.full && e.incHi() && (p.window == 0) {
+		e.write(1 << e.litWidth)
+		e.reset()
+	}
+	e.write(1<<e.litWidth + 1)
+	e.flush()
+}
+
+// reset empties the code table.
+func (e *lzwEncoder) reset() {
+	e.width = e.litWidth + 1
+	e.hi = 1<<e.litWidth + 1
+	e.overflow = 1 << e.width
+	e.full = false
+	e.table = [lzwTableSize]uint32{}
+	e.numRecent, e.recentBits, e.recentPixels = 0, 0, 0
+}
+
+// incHi increments the next implied code, as compress/lzw does, returning
+// whether the code table is now full.
+func (e *lzwEncoder) incHi() bool {
+	e.hi++
+	if e.hi == e.overflow {
+		e.width++
+		e.overflow <<= 1
+	}
+	return e.hi == maxCode
+}
+
+// shouldClear records that a code of n pixels was sent while the code table
+// was full and returns whether to clear the table.
+func (e *lzwEncoder) shouldClear(n int, p clearPolicy) bool {
+	if len(e.recent) < p.window {
+		e.recent = make([][2]int, p.window)
+	}
+	r := &e.recent[e.numRecent%p.window]
+	if e.numRecent >= p.window {
+		e.recentBits -= r[0]
+		e.recentPixels -= r[1]
+	}
+	*r = [2]int{int(e.width), n}
+	e.recentBits += int(e.width)
+	e.recentPixels += n
+	e.numRecent++
+	return (e.numRecent >= p.window) &&
+		(float64(e.recentBits) > p.bitsPerPixel*float64(e.recentPixels))
+}
+
+func (e *lzwEncoder) write(code uint32) {
+	e.bits |= code << e.nBits
+	e.nBits += e.width
+	for e.nBits >= 8 {
+		e.out = append(e.out, uint8(e.bits))
+		e.bits >>= 8
+		e.nBits -= 8
+	}
+}
+
+func (e *lzwEncoder) flush() {
+	if e.nBits > 0 {
+		e.out = append(e.out, uint8(e.bits))
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package animgif
+
+import (
+	"image/color"
+	"sort"
+)
+
+// histogramEntry is a color, how many pixels have that color and its rank,
+// which orders the palette.
+type histogramEntry struct {
+	c    color.RGBA
+	n    int
+	rank int
+}
+
+// buildPalette returns a palette of at most maxColors colors for the colors
+// in the histogram, along with the palette index for each of those colors.
+//
+// If there are at most maxColors colors then the palette is exact. Otherwise,
+// it uses median cut quantization
+// (https://en.wikipedia.org/wiki/Median_cut), weighted by how many pixels
+// have each color, and each color maps to the nearest palette color.
+//
+// Either way, the palette is sorted by rank and then with the most common
+// colors first, so that the result doesn't depend on Go's random map
+// iteration order.
+func buildPalette(histogram map[color.RGBA]histogramEntry, maxColors int) (color.Palette, map[color.RGBA]uint8) {
+	entries := make([]histogramEntry, 0, len(histogram))
+	for _, e := range histogram {
+		entries = append(entries, e)
+	}
+	sortEntries(entries)
+
+	indexes := make(map[color.RGBA]uint8, len(entries))
+	if len(entries) <= maxColors {
+		palette := make(color.Palette, len(entries))
+		for i, e := range entries {
+			palette[i] = e.c
+			indexes[e.c] = uint8(i)
+		}
+		return palette, indexes
+	}
+
+	boxes := [][]histogramEntry{entries}
+	for len(boxes) < maxColors {
+		// Split the box whose widest color channel, weighted by its number of
+		// pixels, is largest.
+		best, bestScore, bestChannel := -1, 0, 0
+		for i, b := range boxes {
+			if len(b) < 2 {
+				continue
+			}
+			channel, width := widestChannel(b)
+			score := width * pixelCount(b)
+			if bestScore < score {
+				best, bestScore, bestChannel = i, score, channel
+			}
+		}
+		if best < 0 {
+			break
+		}
+		lo, hi := splitBox(boxes[best], bestChannel)
+		boxes[best] = lo
+		boxes = append(boxes, hi)
+	}
+
+	means := make([]histogramEntry, len(boxes))
+	for i, b := range boxes {
+		means[i] = mean(b)
+	}
+	sortEntries(means)
+	palette := make(color.Palette, len(means))
+	for i, m := range means {
+		palette[i] = m.c
+	}
+	for _, e := range entries {
+		indexes[e.c] = uint8(nearest(palette, e.c))
+	}
+	return palette, indexes
+}
+
+// sortEntries sorts by rank, then by decreasing pixel count and then by
+// color.
+func sortEntries(entries []histogramEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].rank != entries[j].rank {
+			return entries[i].rank < entries[j].rank
+		} else if entries[i].n != entries[j].n {
+			return entries[i].n > entries[j].n
+		}
+		return key(entries[i].c) < key(entries[j].c)
+	})
+}
+
+func key(c color.RGBA) uint32 {
+	return (uint32(c.R) << 24) | (uint32(c.G) << 16) | (uint32(c.B) << 8) | uint32(c.A)
+}
+
+func channel(c color.RGBA, ch int) int {
+	switch ch {
+	case 0:
+		return int(c.R)
+	case 1:
+		return int(c.G)
+	}
+	return int(c.B)
+}
+
+// widestChannel returns which of the red, green or blue channels (0, 1 or 2)
+// has the largest range of values in the box, and that range.
+func widestChannel(box []histogramEntry) (ch int, width int) {
+	for i := 0; i < 3; i++ {
+		lo, hi := 255, 0
+		for _, e := range box {
+			v := channel(e.c, i)
+			if lo > v {
+				lo = v
+			}
+			if hi < v {
+				hi = v
+			}
+		}
+		if width < hi-lo {
+			ch, width = i, hi-lo
+		}
+	}
+	return ch, width
+}
+
+func pixelCount(box []histogramEntry) int {
+	n := 0
+	for _, e := range box {
+		n += e.n
+	}
+	return n
+}
+
+// splitBox splits a box (of at least two colors) in two at the weighted
+// median of the given channel. Both halves are non-empty.
+func splitBox(box []histogramEntry, ch int) (lo []histogramEntry, hi []histogramEntry) {
+	sort.Slice(box, func(i, j int) bool {
+		if vi, vj := channel(box[i].c, ch), channel(box[j].c, ch); vi != vj {
+			return vi < vj
+		}
+		return key(box[i].c) < key(box[j].c)
+	})
+	half, n, i := pixelCount(box)/2, box[0].n, 0
+	for (n < half) && (i < len(box)-2) {
+		i++
+		n += box[i].n
+	}
+	return box[:i+1], box[i+1:]
+}
+
+// mean returns the average color in the box, weighted by pixel count.
+func mean(box []histogramEntry) histogramEntry {
+	r, g, b, a, n, rank := 0, 0, 0, 0, 0, box[0].rank
+	for _, e := range box {
+		if rank > e.rank {
+			rank = e.rank
+		}
+		r += int(e.c.R) * e.n
+		g += int(e.c.G) * e.n
+		b += int(e.c.B) * e.n
+		a += int(e.c.A) * e.n
+		n += e.n
+	}
+	return histogramEntry{
+		c: color.RGBA{
+			uint8((r + n/2) / n),
+			uint8((g + n/2) / n),
+			uint8((b + n/2) / n),
+			uint8((a + n/2) / n),
+		},
+		n:    n,
+		rank: rank,
+	}
+}
+
+// nearest returns the index of the palette color closest to c, in (squared)
+// Euclidean distance.
+func nearest(palette color.Palette, c color.RGBA) int {
+	best, bestDist := 0, 1<<62
+	for i, p := range palette {
+		q := p.(color.RGBA)
+		dr := int(c.R) - int(q.R)
+		dg := int(c.G) - int(q.G)
+		db := int(c.B) - int(q.B)
+		if d := (dr * dr) + (dg * dg) + (db * db); bestDist > d {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,161 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package animgif
+
+import (
+	"bufio"
+	"errors"
+	"image/color"
+	"image/gif"
+	"io"
+)
+
+// encode writes g, which must have a global palette, like gif.EncodeAll does
+// but with three differences that make for smaller animations:
+//
+//   - A frame's palette can be the global palette with one of its entries
+//     replaced by the transparent color, without needing a local color table.
+//   - A frame's LZW literal width depends on the largest palette index that
+//     the frame uses, not on the size of its palette.
+//   - The LZW encoder doesn't always clear its code table when it is full.
+//
+// The GIF loops forever.
+func encode(w io.Writer, g *gif.GIF) error {
+	global, ok := g.Config.ColorModel.(color.Palette)
+	if !ok || (len(global) == 0) || (len(global) > 256) {
+		return errors.New("animgif: invalid global palette")
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString("GIF89a")
+	writeUint16(bw, g.Config.Width)
+	writeUint16(bw, g.Config.Height)
+	bw.Write([]byte{0xF0 | tableBits(len(global)), 0x00, 0x00})
+	writeColorTable(bw, global)
+	if len(g.Image) > 1 {
+		bw.Write([]byte{0x21, 0xFF, 0x0B})
+		bw.WriteString("NETSCAPE2.0")
+		bw.Write([]byte{0x03, 0x01, 0x00, 0x00, 0x00})
+	}
+
+	for i, p := range g.Image {
+		flags, t := g.Disposal[i]<<2, uint8(0)
+		if ti := transparentIndex(p.Palette); ti >= 0 {
+			flags, t = flags|0x01, uint8(ti)
+		}
+		bw.Write([]byte{0x21, 0xF9, 0x04, flags})
+		writeUint16(bw, g.Delay[i])
+		bw.Write([]byte{t, 0x00})
+
+		r := p.Rect
+		bw.WriteByte(0x2C)
+		writeUint16(bw, r.Min.X)
+		writeUint16(bw, r.Min.Y)
+		writeUint16(bw, r.Dx())
+		writeUint16(bw, r.Dy())
+		if isGlobal(p.Palette, global) {
+			bw.WriteByte(0x00)
+		} else {
+			bw.WriteByte(0x80 | tableBits(len(p.Palette)))
+			writeColorTable(bw, p.Palette)
+		}
+
+		pix := pixels(p)
+		bw.WriteByte(uint8(pixelWidth(pix)))
+		writeBlocks(bw, compress(pix))
+	}
+	bw.WriteByte(0x3B)
+	return bw.Flush()
+}
+
+// isGlobal returns whether a frame's palette can use the global color table:
+// whether they're the same, other than for the frame's transparent entry.
+func isGlobal(p color.Palette, global color.Palette) bool {
+	if len(p) != len(global) {
+		return false
+	}
+	t := transparentIndex(p)
+	for i, c := range p {
+		if (i != t) && (c != global[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// transparentIndex returns the index of the first fully transparent entry in
+// p, the same way that image/gif does, or -1 if there is none.
+func transparentIndex(p color.Palette) int {
+	for i, c := range p {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// tableBits returns the 3-bit size field for a color table of n colors: the
+// table holds 2<<tableBits colors, padded with black.
+func tableBits(n int) uint8 {
+	b := uint8(0)
+	for (2 << b) < n {
+		b++
+	}
+	return b
+}
+
+// tableSize returns the number of bytes in a color table of n colors.
+func tableSize(n int) int {
+	return 6 << tableBits(n)
+}
+
+func writeColorTable(w *bufio.Writer, p color.Palette) {
+	for i := 0; i < (2 << tableBits(len(p))); i++ {
+		c := color.RGBA{}
+		if i < len(p) {
+			c = color.RGBAModel.Convert(p[i]).(color.RGBA)
+		}
+		w.Write([]byte{c.R, c.G, c.B})
+	}
+}
+
+func writeUint16(w *bufio.Writer, x int) {
+	w.Write([]byte{uint8(x), uint8(x >> 8)})
+}
+
+// pixelWidth returns the LZW literal width for the palette indexes pix.
+func pixelWidth(pix []uint8) int {
+	max := uint8(0)
+	for _, x := range pix {
+		if max < x {
+			max = x
+		}
+	}
+	return litWidth(int(max) + 1)
+}
+
+// writeBlocks writes data as GIF sub-blocks of up to 255 bytes each, followed
+// by an empty block.
+func writeBlocks(w *bufio.Writer, data []byte) {
+	for len(data) > 0 {
+		n := len(data)
+		if n > 255 {
+			n = 255
+		}
+		w.WriteByte(uint8(n))
+		w.Write(data[:n])
+		data = data[n:]
+	}
+	w.WriteByte(0x00)
+}
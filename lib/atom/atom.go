@@ -0,0 +1,256 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package atom validates Atom syndication feeds.
+//
+// It checks the structural rules of RFC 4287 (which elements are required,
+// which may appear at most once and which values must be unique) rather than
+// every detail of the specification. It is intended as a safety net for
+// generated feeds, not as a general purpose feed parser.
+package atom
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+)
+
+// Namespace is the Atom XML namespace.
+const Namespace = "http://www.w3.org/2005/Atom"
+
+type feed struct {
+	XMLName  xml.Name
+	ID       []string   `xml:"id"`
+	Title    []text     `xml:"title"`
+	Subtitle []text     `xml:"subtitle"`
+	Updated  []string   `xml:"updated"`
+	Author   []person   `xml:"author"`
+	Link     []link     `xml:"link"`
+	Category []category `xml:"category"`
+	Entry    []entry    `xml:"entry"`
+}
+
+type entry struct {
+	ID        []string   `xml:"id"`
+	Title     []text     `xml:"title"`
+	Updated   []string   `xml:"updated"`
+	Published []string   `xml:"published"`
+	Author    []person   `xml:"author"`
+	Link      []link     `xml:"link"`
+	Category  []category `xml:"category"`
+	Summary   []text     `xml:"summary"`
+	Content   []text     `xml:"content"`
+}
+
+type text struct {
+	Type string `xml:"type,attr"`
+	Src  string `xml:"src,attr"`
+}
+
+type person struct {
+	Name []string `xml:"name"`
+}
+
+type link struct {
+	Href     string `xml:"href,attr"`
+	Rel      string `xml:"rel,attr"`
+	Type     string `xml:"type,attr"`
+	HrefLang string `xml:"hreflang,attr"`
+}
+
+type category struct {
+	Term string `xml:"term,attr"`
+}
+
+// Validate returns an error if src is not well-formed XML or is not a valid
+// Atom feed document.
+func Validate(src []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(src))
+	d.Strict = true
+	f := feed{}
+	if err := d.Decode(&f); err != nil {
+		return fmt.Errorf("atom: %v", err)
+	}
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("atom: %v", err)
+		}
+		if c, ok := tok.(xml.CharData); ok && (len(bytes.TrimSpace(c)) == 0) {
+			continue
+		} else if _, ok := tok.(xml.Comment); ok {
+			continue
+		}
+		return fmt.Errorf("atom: content after the root element")
+	}
+	if (f.XMLName.Space != Namespace) || (f.XMLName.Local != "feed") {
+		return fmt.Errorf("atom: root element is not an Atom feed")
+	}
+
+	if err := checkCommon("feed", f.ID, f.Title, f.Updated, f.Link, f.Category); err != nil {
+		return err
+	}
+	if err := checkTexts("feed", "subtitle", f.Subtitle, false); err != nil {
+		return err
+	}
+	if err := checkPeople("feed", f.Author); err != nil {
+		return err
+	}
+
+	ids := map[string]bool{}
+	for i := range f.Entry {
+		e := &f.Entry[i]
+		where := fmt.Sprintf("entry #%d", i+1)
+		if len(e.ID) == 1 {
+			where = fmt.Sprintf("entry %q", e.ID[0])
+		}
+
+		if err := checkCommon(where, e.ID, e.Title, e.Updated, e.Link, e.Category); err != nil {
+			return err
+		}
+		if ids[e.ID[0]] {
+			return fmt.Errorf("atom: %s: duplicate id", where)
+		}
+		ids[e.ID[0]] = true
+
+		if len(e.Published) > 1 {
+			return fmt.Errorf("atom: %s: more than one published element", where)
+		} else if len(e.Published) == 1 {
+			if err := checkDate(where, "published", e.Published[0]); err != nil {
+				return err
+			}
+		}
+		if err := checkTexts(where, "summary", e.Summary, false); err != nil {
+			return err
+		}
+		if err := checkTexts(where, "content", e.Content, true); err != nil {
+			return err
+		}
+		if err := checkPeople(where, e.Author); err != nil {
+			return err
+		}
+
+		// An entry must have an author unless its feed has one.
+		if (len(e.Author) == 0) && (len(f.Author) == 0) {
+			return fmt.Errorf("atom: %s: no author (and the feed has none)", where)
+		}
+		// An entry without content must link to it. Out-of-line content
+		// (with a src attribute) also needs a summary.
+		if len(e.Content) == 0 {
+			if !hasAlternate(e.Link) {
+				return fmt.Errorf("atom: %s: no content and no alternate link", where)
+			}
+		} else if (e.Content[0].Src != "") && (len(e.Summary) == 0) {
+			return fmt.Errorf("atom: %s: out-of-line content but no summary", where)
+		}
+	}
+	return nil
+}
+
+// checkCommon checks the rules that apply to both feeds and entries: exactly
+// one id, title and updated element, and well-formed links and categories.
+func checkCommon(where string, id []string, title []text, updated []string, links []link, categories []category) error {
+	if len(id) != 1 {
+		return fmt.Errorf("atom: %s: want exactly one id element, got %d", where, len(id))
+	} else if u, err := url.Parse(id[0]); (err != nil) || !u.IsAbs() {
+		return fmt.Errorf("atom: %s: id is not an absolute IRI", where)
+	}
+
+	if len(title) != 1 {
+		return fmt.Errorf("atom: %s: want exactly one title element, got %d", where, len(title))
+	} else if err := checkTexts(where, "title", title, false); err != nil {
+		return err
+	}
+
+	if len(updated) != 1 {
+		return fmt.Errorf("atom: %s: want exactly one updated element, got %d", where, len(updated))
+	} else if err := checkDate(where, "updated", updated[0]); err != nil {
+		return err
+	}
+
+	// At most one alternate link may share the same type and hreflang.
+	type key struct{ typ, hrefLang string }
+	alternates := map[key]bool{}
+	for _, l := range links {
+		if l.Href == "" {
+			return fmt.Errorf("atom: %s: link has no href", where)
+		}
+		if (l.Rel != "") && (l.Rel != "alternate") {
+			continue
+		}
+		k := key{l.Type, l.HrefLang}
+		if alternates[k] {
+			return fmt.Errorf("atom: %s: more than one alternate link with type %q and hreflang %q",
+				where, l.Type, l.HrefLang)
+		}
+		alternates[k] = true
+	}
+
+	for _, c := range categories {
+		if c.Term == "" {
+			return fmt.Errorf("atom: %s: category has no term", where)
+		}
+	}
+	return nil
+}
+
+// checkTexts checks that there is at most one of the named text construct
+// element and that its type is valid. Only content elements may have other
+// (MIME) types.
+func checkTexts(where string, name string, texts []text, isContent bool) error {
+	if len(texts) > 1 {
+		return fmt.Errorf("atom: %s: more than one %s element", where, name)
+	}
+	for _, t := range texts {
+		switch t.Type {
+		case "", "text", "html", "xhtml":
+			// No-op.
+		default:
+			if !isContent {
+				return fmt.Errorf("atom: %s: %s has invalid type %q", where, name, t.Type)
+			}
+		}
+	}
+	return nil
+}
+
+func checkPeople(where string, people []person) error {
+	for _, p := range people {
+		if len(p.Name) != 1 {
+			return fmt.Errorf("atom: %s: author needs exactly one name element, got %d", where, len(p.Name))
+		}
+	}
+	return nil
+}
+
+func checkDate(where string, name string, s string) error {
+	if _, err := time.Parse(time.RFC3339, s); err != nil {
+		return fmt.Errorf("atom: %s: %s is not an RFC 3339 date: %q", where, name, s)
+	}
+	return nil
+}
+
+func hasAlternate(links []link) bool {
+	for _, l := range links {
+		if (l.Rel == "") || (l.Rel == "alternate") {
+			return true
+		}
+	}
+	return false
+}
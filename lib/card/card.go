@@ -0,0 +1,163 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package card makes social cards: the preview images that sites like
+// Twitter and Mastodon show (per the Open Graph and Twitter card meta tags)
+// when a blog post is linked to.
+//
+// A card is either a thumbnail of one of the post's own images or, for posts
+// without images, the post's title rendered in the Go fonts.
+package card
+
+import (
+	"image"
+	"image/color"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Width and Height are the size of a card, in pixels. That's what Facebook
+// recommends and Twitter's "summary_large_image" cards use the same 1.91:1
+// aspect ratio.
+const (
+	Width  = 1200
+	Height = 630
+)
+
+// Thumbnail crops src, keeping its center, to the card's aspect ratio and
+// scales it to the card's size. Transparent parts of src become white.
+func Thumbnail(src image.Image) *image.RGBA {
+	sr := src.Bounds()
+	if sr.Dx()*Height > sr.Dy()*Width {
+		// src is wider than the card. Crop its left and right.
+		w := sr.Dy() * Width / Height
+		sr.Min.X += (sr.Dx() - w) / 2
+		sr.Max.X = sr.Min.X + w
+	} else {
+		// src is taller than the card. Crop its top and bottom.
+		h := sr.Dx() * Height / Width
+		sr.Min.Y += (sr.Dy() - h) / 2
+		sr.Max.Y = sr.Min.Y + h
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, sr, draw.Over, nil)
+	return dst
+}
+
+var (
+	background = color.RGBA{0xF6, 0xF8, 0xFA, 0xFF}
+	accent     = color.RGBA{0x73, 0x5C, 0x0F, 0xFF}
+	foreground = color.RGBA{0x1F, 0x23, 0x28, 0xFF}
+	subdued    = color.RGBA{0x55, 0x55, 0x55, 0xFF}
+)
+
+// Layout, in pixels.
+const (
+	margin       = 80
+	accentWidth  = 24
+	titleSize    = 64
+	titleLeading = 80
+	titleLines   = 4
+	footerSize   = 32
+)
+
+// TextCard renders a card showing a title (word-wrapped, in Go Bold) and a
+// footer line (in Go Regular), such as the site's name and the post's date.
+func TextCard(title string, footer string) (*image.RGBA, error) {
+	titleFace, err := newFace(gobold.TTF, titleSize)
+	if err != nil {
+		return nil, err
+	}
+	defer titleFace.Close()
+	footerFace, err := newFace(goregular.TTF, footerSize)
+	if err != nil {
+		return nil, err
+	}
+	defer footerFace.Close()
+
+	dst := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{background}, image.Point{}, draw.Src)
+	draw.Draw(dst, image.Rect(0, 0, accentWidth, Height), &image.Uniform{accent}, image.Point{}, draw.Src)
+
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  &image.Uniform{foreground},
+		Face: titleFace,
+	}
+	for i, line := range wrap(d, title, Width-2*margin) {
+		d.Dot = fixed.P(margin, margin+titleSize+(i*titleLeading))
+		d.DrawString(line)
+	}
+
+	d.Src = &image.Uniform{subdued}
+	d.Face = footerFace
+	d.Dot = fixed.P(margin, Height-margin)
+	d.DrawString(footer)
+	return dst, nil
+}
+
+func newFace(ttf []byte, size float64) (font.Face, error) {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		return nil, err
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingNone,
+	})
+}
+
+// wrap splits text into lines that are at most width pixels wide, breaking
+// between words. If that takes more than titleLines lines, the last one ends
+// with an ellipsis.
+func wrap(d *font.Drawer, text string, width int) (lines []string) {
+	maxWidth := fixed.I(width)
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line == "" {
+			line = word
+		} else if s := line + " " + word; d.MeasureString(s) <= maxWidth {
+			line = s
+		} else {
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	if len(lines) > titleLines {
+		lines = lines[:titleLines]
+		last := lines[titleLines-1]
+		for d.MeasureString(last+"…") > maxWidth {
+			i := strings.LastIndexByte(last, ' ')
+			if i < 0 {
+				break
+			}
+			last = last[:i]
+		}
+		lines[titleLines-1] = last + "…"
+	}
+	return lines
+}
@@ -0,0 +1,244 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diff computes line-based differences between two texts and formats
+// them as unified diffs, like "diff -u" does.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// contextLines is the number of unchanged lines shown around each change.
+const contextLines = 3
+
+// maxEditDistance bounds the work (and memory) spent finding a minimal diff.
+// Beyond it, the differing middle section is shown as one big replacement.
+const maxEditDistance = 2000
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	line string
+}
+
+// Unified returns a unified diff that turns a into b, labeling them aName and
+// bName. It returns nil if a and b are equal.
+func Unified(aName string, bName string, a []byte, b []byte) []byte {
+	if bytes.Equal(a, b) {
+		return nil
+	}
+	ops := lineDiff(splitLines(a), splitLines(b))
+
+	dst := bytes.NewBuffer(nil)
+	fmt.Fprintf(dst, "--- %s\n+++ %s\n", aName, bName)
+	for i := 0; i < len(ops); {
+		// Find the next change.
+		for (i < len(ops)) && (ops[i].kind == opEqual) {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		// Extend the hunk until there are more than 2*contextLines unchanged
+		// lines in a row (or the end).
+		start := i - contextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != opEqual {
+				end = j + 1
+			} else if j-end >= 2*contextLines {
+				break
+			}
+		}
+		end += contextLines
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		writeHunk(dst, ops, start, end)
+		i = end
+	}
+	return dst.Bytes()
+}
+
+// writeHunk writes the "@@ -l,s +l,s @@" header and lines of ops[start:end].
+func writeHunk(dst *bytes.Buffer, ops []op, start int, end int) {
+	aLine, bLine := 1, 1
+	for _, o := range ops[:start] {
+		if o.kind != opInsert {
+			aLine++
+		}
+		if o.kind != opDelete {
+			bLine++
+		}
+	}
+	aCount, bCount := 0, 0
+	for _, o := range ops[start:end] {
+		if o.kind != opInsert {
+			aCount++
+		}
+		if o.kind != opDelete {
+			bCount++
+		}
+	}
+	fmt.Fprintf(dst, "@@ -%s +%s @@\n", hunkRange(aLine, aCount), hunkRange(bLine, bCount))
+
+	for _, o := range ops[start:end] {
+		dst.WriteByte(byte(o.kind))
+		dst.WriteString(o.line)
+		if (len(o.line) == 0) || (o.line[len(o.line)-1] != '\n') {
+			dst.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats a line range the way GNU diff does: an empty range is
+// given by the line before it and a single line range omits its count.
+func hunkRange(line int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line-1)
+	} else if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+// splitLines splits s after each '\n'. The final line may not end with one.
+func splitLines(s []byte) []string {
+	lines := []string(nil)
+	for len(s) > 0 {
+		i := bytes.IndexByte(s, '\n') + 1
+		if i == 0 {
+			i = len(s)
+		}
+		lines = append(lines, string(s[:i]))
+		s = s[i:]
+	}
+	return lines
+}
+
+// lineDiff returns the edit script that turns a into b. After trimming any
+// common prefix and suffix, it uses Myers' O(ND) algorithm.
+func lineDiff(a []string, b []string) []op {
+	prefix := 0
+	for (prefix < len(a)) && (prefix < len(b)) && (a[prefix] == b[prefix]) {
+		prefix++
+	}
+	suffix := 0
+	for (suffix < len(a)-prefix) && (suffix < len(b)-prefix) &&
+		(a[len(a)-1-suffix] == b[len(b)-1-suffix]) {
+		suffix++
+	}
+
+	ops := make([]op, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, op{opEqual, line})
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, op{opEqual, line})
+	}
+	return ops
+}
+
+func myers(a []string, b []string) []op {
+	n, m := len(a), len(b)
+	maxD := n + m
+	if maxD > maxEditDistance {
+		maxD = maxEditDistance
+	}
+
+	// v[offset+k] is the furthest x reached on diagonal k (where k = x - y).
+	// trace[d] holds a copy of v[offset-d:offset+d+1] from before round d.
+	offset := maxD + 1
+	v := make([]int, 2*offset+1)
+	trace := [][]int(nil)
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			x := 0
+			if (k == -d) || ((k != d) && (v[offset+k-1] < v[offset+k+1])) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for (x < n) && (y < m) && (a[x] == b[y]) {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if (x >= n) && (y >= m) {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+
+	// Give up on a minimal diff: delete all of a and insert all of b.
+	ops := make([]op, 0, n+m)
+	for _, line := range a {
+		ops = append(ops, op{opDelete, line})
+	}
+	for _, line := range b {
+		ops = append(ops, op{opInsert, line})
+	}
+	return ops
+}
+
+func backtrack(a []string, b []string, trace [][]int) []op {
+	ops := []op(nil)
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if (k == -d) || ((k != d) && (v[d+k-1] < v[d+k+1])) {
+			prevK = k + 1
+		}
+		prevX := v[d+prevK]
+		prevY := prevX - prevK
+		for (x > prevX) && (y > prevY) {
+			x, y = x-1, y-1
+			ops = append(ops, op{opEqual, a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, op{opInsert, b[y]})
+		} else {
+			x--
+			ops = append(ops, op{opDelete, a[x]})
+		}
+	}
+	for (x > 0) && (y > 0) {
+		x, y = x-1, y-1
+		ops = append(ops, op{opEqual, a[x]})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -0,0 +1,399 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package epub writes EPUB 3 e-books (https://www.w3.org/TR/epub-33/).
+//
+// An EPUB file is a zip archive holding XHTML chapters, a navigation document
+// (the table of contents), a package document (listing every file, in reading
+// order) and any other resources, such as images and fonts. This package
+// writes all of the boilerplate. The caller provides each chapter's body.
+//
+// The output is reproducible: writing the same Book twice gives the same
+// bytes.
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Book is an e-book.
+type Book struct {
+	// Identifier uniquely identifies the book, such as a URL.
+	Identifier string
+	Title      string
+	Author     string
+
+	// Language is a BCP 47 language tag, such as "en".
+	Language string
+
+	// Modified is when the book's contents were last modified. It is also
+	// used as the zip archive's file modification times.
+	Modified time.Time
+
+	// Stylesheet, if non-empty, is CSS that applies to every chapter.
+	Stylesheet string
+
+	// Chapters are the book's chapters, in reading order.
+	Chapters []*Chapter
+
+	// Resources are the other files, such as images and fonts, that the
+	// chapters or stylesheet refer to.
+	Resources []*Resource
+}
+
+// Chapter is one XHTML file of the book.
+type Chapter struct {
+	// Filename is the chapter's name within the book, such as "part-1.xhtml".
+	// Links from other chapters should use this name.
+	Filename string
+
+	// Title is the chapter's plain text title.
+	Title string
+
+	// Body is the chapter's XHTML content, which goes inside the <body>
+	// element. It must be well-formed XML, apart from not needing a single
+	// root element.
+	Body []byte
+
+	// Headings are the chapter's headings, listed under the chapter in the
+	// book's table of contents.
+	Headings []Heading
+}
+
+// Heading is an entry in the table of contents that links to part of a
+// chapter.
+type Heading struct {
+	// Level is the heading level, such as 2 for an <h2>. Headings are nested
+	// by level in the table of contents.
+	Level int
+
+	// ID is the heading element's id attribute.
+	ID string
+
+	// Title is the heading's plain text.
+	Title string
+}
+
+// Resource is a non-chapter file of the book.
+type Resource struct {
+	// Filename is the resource's name within the book, such as
+	// "images/foo.png". Chapters should refer to it by this name.
+	Filename string
+
+	// MediaType is the resource's MIME type, such as "image/png".
+	MediaType string
+
+	Data []byte
+}
+
+// MediaType returns the MIME type of a resource with the given filename
+// extension, such as ".png", or "" for an unsupported extension. It covers
+// the EPUB core media types that blog posts use.
+func MediaType(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".gif":
+		return "image/gif"
+	case ".jpeg", ".jpg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".svg":
+		return "image/svg+xml"
+	case ".css":
+		return "text/css"
+	case ".ttf":
+		return "font/ttf"
+	case ".otf":
+		return "font/otf"
+	case ".woff":
+		return "font/woff"
+	case ".woff2":
+		return "font/woff2"
+	}
+	return ""
+}
+
+// The book's own files, within the zip archive. Chapters and resources are
+// relative to contentDir.
+const (
+	contentDir     = "EPUB"
+	packageName    = "package.opf"
+	navName        = "nav.xhtml"
+	stylesheetName = "style.css"
+)
+
+// Write writes the book as an EPUB file.
+func (b *Book) Write(w io.Writer) error {
+	if err := b.check(); err != nil {
+		return err
+	}
+
+	files := []zipFile{
+		{"META-INF/container.xml", []byte(containerXML)},
+		{contentDir + "/" + packageName, b.packageDocument()},
+		{contentDir + "/" + navName, b.navDocument()},
+	}
+	if b.Stylesheet != "" {
+		files = append(files, zipFile{contentDir + "/" + stylesheetName, []byte(b.Stylesheet)})
+	}
+	for _, c := range b.Chapters {
+		files = append(files, zipFile{contentDir + "/" + c.Filename, b.chapterDocument(c)})
+	}
+	for _, r := range b.Resources {
+		files = append(files, zipFile{contentDir + "/" + r.Filename, r.Data})
+	}
+
+	zw := zip.NewWriter(w)
+	// The "mimetype" file must come first and be uncompressed, so that tools
+	// can identify an EPUB file by its first few bytes.
+	if err := b.writeZipFile(zw, "mimetype", []byte("application/epub+zip"), zip.Store); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := b.writeZipFile(zw, f.name, f.data, zip.Deflate); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+type zipFile struct {
+	name string
+	data []byte
+}
+
+func (b *Book) writeZipFile(zw *zip.Writer, name string, data []byte, method uint16) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   method,
+		Modified: b.Modified.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(data)
+	return err
+}
+
+// check checks that the book's file names are unique and usable and that each
+// chapter is well-formed XHTML.
+func (b *Book) check() error {
+	if (b.Identifier == "") || (b.Title == "") || (b.Language == "") {
+		return errors.New("epub: missing identifier, title or language")
+	} else if len(b.Chapters) == 0 {
+		return errors.New("epub: no chapters")
+	}
+	seen := map[string]bool{packageName: true, navName: true, stylesheetName: true}
+	checkName := func(name string) error {
+		if (name == "") || (name != path.Clean(name)) || path.IsAbs(name) ||
+			strings.HasPrefix(name, "../") || strings.ContainsAny(name, "#?\\") {
+			return fmt.Errorf("epub: invalid file name %q", name)
+		} else if seen[name] {
+			return fmt.Errorf("epub: duplicate file name %q", name)
+		}
+		seen[name] = true
+		return nil
+	}
+	for _, c := range b.Chapters {
+		if err := checkName(c.Filename); err != nil {
+			return err
+		}
+		if err := checkXML(b.chapterDocument(c)); err != nil {
+			return fmt.Errorf("epub: %s: %v", c.Filename, err)
+		}
+	}
+	for _, r := range b.Resources {
+		if err := checkName(r.Filename); err != nil {
+			return err
+		} else if r.MediaType == "" {
+			return fmt.Errorf("epub: %s: no media type", r.Filename)
+		}
+	}
+	return nil
+}
+
+// checkXML checks that src is well-formed XML. XHTML's named entities, other
+// than XML's own five, aren't allowed.
+func checkXML(src []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(src))
+	d.Strict = true
+	for {
+		if _, err := d.Token(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+const containerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="` + contentDir + "/" + packageName + `" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+func (b *Book) packageDocument() []byte {
+	dst := &bytes.Buffer{}
+	dst.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	dst.WriteString(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid" xml:lang="` +
+		escape(b.Language) + `">` + "\n")
+	dst.WriteString(`  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	dst.WriteString(`    <dc:identifier id="uid">` + escape(b.Identifier) + "</dc:identifier>\n")
+	dst.WriteString(`    <dc:title>` + escape(b.Title) + "</dc:title>\n")
+	if b.Author != "" {
+		dst.WriteString(`    <dc:creator>` + escape(b.Author) + "</dc:creator>\n")
+	}
+	dst.WriteString(`    <dc:language>` + escape(b.Language) + "</dc:language>\n")
+	dst.WriteString(`    <meta property="dcterms:modified">` +
+		b.Modified.UTC().Format("2006-01-02T15:04:05Z") + "</meta>\n")
+	dst.WriteString("  </metadata>\n")
+
+	dst.WriteString("  <manifest>\n")
+	dst.WriteString(`    <item id="nav" href="` + navName + `" media-type="application/xhtml+xml" properties="nav"/>` + "\n")
+	if b.Stylesheet != "" {
+		dst.WriteString(`    <item id="style" href="` + stylesheetName + `" media-type="text/css"/>` + "\n")
+	}
+	for i, c := range b.Chapters {
+		fmt.Fprintf(dst, `    <item id="chapter-%d" href="%s" media-type="application/xhtml+xml"/>`+"\n",
+			i+1, escape(c.Filename))
+	}
+	for i, r := range b.Resources {
+		fmt.Fprintf(dst, `    <item id="resource-%d" href="%s" media-type="%s"/>`+"\n",
+			i+1, escape(r.Filename), escape(r.MediaType))
+	}
+	dst.WriteString("  </manifest>\n")
+
+	dst.WriteString("  <spine>\n")
+	for i := range b.Chapters {
+		fmt.Fprintf(dst, `    <itemref idref="chapter-%d"/>`+"\n", i+1)
+	}
+	dst.WriteString("  </spine>\n")
+	dst.WriteString("</package>\n")
+	return dst.Bytes()
+}
+
+// writeXHTMLStart writes the start of an XHTML document, up to and including
+// the <body> tag.
+func (b *Book) writeXHTMLStart(dst *bytes.Buffer, title string) {
+	dst.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	dst.WriteString("<!DOCTYPE html>\n")
+	dst.WriteString(`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"` +
+		` xml:lang="` + escape(b.Language) + `" lang="` + escape(b.Language) + `">` + "\n")
+	dst.WriteString("<head>\n")
+	dst.WriteString("<title>" + escape(title) + "</title>\n")
+	if b.Stylesheet != "" {
+		dst.WriteString(`<link rel="stylesheet" type="text/css" href="` + stylesheetName + `"/>` + "\n")
+	}
+	dst.WriteString("</head>\n")
+	dst.WriteString("<body>\n")
+}
+
+func (b *Book) chapterDocument(c *Chapter) []byte {
+	dst := &bytes.Buffer{}
+	b.writeXHTMLStart(dst, c.Title)
+	dst.Write(c.Body)
+	dst.WriteString("</body>\n</html>\n")
+	return dst.Bytes()
+}
+
+// navDocument returns the navigation document, whose table of contents lists
+// each chapter and, nested underneath, its headings.
+func (b *Book) navDocument() []byte {
+	dst := &bytes.Buffer{}
+	b.writeXHTMLStart(dst, b.Title)
+	dst.WriteString(`<nav epub:type="toc" id="toc">` + "\n")
+	dst.WriteString("<h1>" + escape(b.Title) + "</h1>\n")
+	dst.WriteString("<ol>\n")
+	for _, c := range b.Chapters {
+		dst.WriteString(`<li><a href="` + escape(c.Filename) + `">` + escape(c.Title) + "</a>")
+		writeHeadings(dst, c)
+		dst.WriteString("</li>\n")
+	}
+	dst.WriteString("</ol>\n")
+	dst.WriteString("</nav>\n")
+	dst.WriteString("</body>\n</html>\n")
+	return dst.Bytes()
+}
+
+// writeHeadings writes a chapter's headings as nested <ol> lists. A list can
+// only nest one level deeper than its parent, even if (say) a level 2 heading
+// is followed by a level 4 heading.
+func writeHeadings(dst *bytes.Buffer, c *Chapter) {
+	if len(c.Headings) == 0 {
+		return
+	}
+	minLevel := c.Headings[0].Level
+	for _, h := range c.Headings {
+		if minLevel > h.Level {
+			minLevel = h.Level
+		}
+	}
+
+	depth := -1
+	for _, h := range c.Headings {
+		d := h.Level - minLevel
+		if d > depth+1 {
+			d = depth + 1
+		}
+		if d > depth {
+			dst.WriteString("\n<ol>\n")
+		} else {
+			dst.WriteString("</li>\n")
+			for ; depth > d; depth-- {
+				dst.WriteString("</ol>\n</li>\n")
+			}
+		}
+		depth = d
+		dst.WriteString(`<li><a href="` + escape(c.Filename+"#"+h.ID) + `">` + escape(h.Title) + "</a>")
+	}
+	dst.WriteString("</li>\n")
+	for ; depth > 0; depth-- {
+		dst.WriteString("</ol>\n</li>\n")
+	}
+	dst.WriteString("</ol>\n")
+}
+
+// escape escapes s for XML text or a double-quoted attribute value.
+func escape(s string) string {
+	return xmlEscaper.Replace(s)
+}
+
+var xmlEscaper = strings.NewReplacer(
+	`&`, "&amp;",
+	`<`, "&lt;",
+	`>`, "&gt;",
+	`"`, "&quot;",
+)
+
+// ChapterFilename returns a conventional name for the i'th chapter, counting
+// from 1, such as "chapter-01.xhtml". The numbers are zero-padded to the same
+// width, so that the names sort in reading order.
+func ChapterFilename(i int, numChapters int) string {
+	width := len(strconv.Itoa(numChapters))
+	if width < 2 {
+		width = 2
+	}
+	return fmt.Sprintf("chapter-%0*d.xhtml", width, i)
+}
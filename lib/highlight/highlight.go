@@ -0,0 +1,213 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package highlight marks up Go, C, C++ and Wuffs source code as HTML.
+//
+// It deliberately doesn't do traditional (Christmas-tree) syntax highlighting,
+// where every keyword, string and number gets its own color. As per
+// blog/2018/colorful-text.md, it only highlights where functions and types are
+// declared, so that a reader can quickly see where each one starts.
+// Declarations are wrapped in a <span class="decl">, which Stylesheet styles.
+//
+// A small lexer skips over comments, string literals and C preprocessor lines,
+// so that braces in those don't confuse the tracking of nesting depth, and so
+// that a comment line doesn't look like a declaration.
+package highlight
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/nigeltao/nigeltao.github.io/lib/markdown"
+)
+
+// Stylesheet is the CSS for the HTML that Write produces.
+const Stylesheet = `pre .decl { color: #735c0f; font-weight: bold }`
+
+// Normalize returns the canonical name ("c", "cpp", "go" or "wuffs") of a
+// supported language, given a fenced code block's language name, such as
+// "c++" or "golang". It returns "" for unsupported languages.
+func Normalize(lang string) string {
+	switch strings.ToLower(lang) {
+	case "c", "h":
+		return "c"
+	case "cc", "cpp", "c++", "cxx", "hh", "hpp":
+		return "cpp"
+	case "go", "golang":
+		return "go"
+	case "wuffs":
+		return "wuffs"
+	}
+	return ""
+}
+
+var (
+	wuffsRegexp = regexp.MustCompile(`(?m)^(pub|pri) (const|func|status|struct|use) `)
+	goRegexp    = regexp.MustCompile(`(?m)^(package \w+$|func [\w(]|type \w+ \S)`)
+	cRegexp     = regexp.MustCompile(`(?m)^(#include [<"]|#define \w|typedef \w|(class|struct|enum|union)( \w+)?.*\{$|` +
+		// A function declaration or definition, "type name(args) {", possibly
+		// with its arguments spread over multiple lines.
+		`[A-Za-z_][\w:<>*& ]*[ *&][A-Za-z_][\w:]*\((.*\)\s*(const\s*)?(\{.*)?|[^()]*,)$|` +
+		// A GNU style function definition, with the type on its own line.
+		`[A-Za-z_]\w*\n[A-Za-z_]\w* ?\()`)
+	cppRegexp = regexp.MustCompile(`\b(class|co_await|co_return|co_yield|namespace|nullptr|template)\b|::|#include <[a-z_]+>`)
+)
+
+// Detect guesses the language of a code block that doesn't say what language
+// it is. It returns "" unless the code clearly looks like a supported
+// language. In particular, shell sessions, hex dumps and diffs return "".
+func Detect(code string) string {
+	if strings.HasPrefix(code, "$ ") || strings.Contains(code, "\n$ ") {
+		return ""
+	}
+	if wuffsRegexp.MatchString(code) {
+		return "wuffs"
+	} else if goRegexp.MatchString(code) {
+		return "go"
+	}
+	for _, m := range cRegexp.FindAllString(code, -1) {
+		// Rule out statements like "if (x)", which otherwise look like a
+		// function declaration "type name(args)".
+		if i := strings.IndexAny(m, " (\n"); (i >= 0) && cStatementKeywords[m[:i]] {
+			continue
+		}
+		if cppRegexp.MatchString(code) {
+			return "cpp"
+		}
+		return "c"
+	}
+	return ""
+}
+
+// Write writes code as HTML to dst, escaping it and highlighting its
+// declarations. lang should be a canonical name, as returned by Normalize or
+// Detect. It returns false, writing nothing, if lang isn't supported.
+func Write(dst *bytes.Buffer, lang string, code string) bool {
+	isDecl := declFuncs[lang]
+	if isDecl == nil {
+		return false
+	}
+	toks := lex(lang, code)
+
+	// Find the declarations: a line that starts, at column 0 and outside of
+	// any braces, with the tokens that isDecl looks for. A declaration is
+	// highlighted up until its body's opening brace, a comment or the end of
+	// the line.
+	spans := [][2]int(nil)
+	depth := 0
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		if (depth == 0) && (t.kind == tokIdent) && ((t.pos == 0) || (code[t.pos-1] == '\n')) {
+			j := i
+			for (j < len(toks)) && (toks[j].kind != tokNewline) {
+				j++
+			}
+			if line := toks[i:j]; isDecl(code, line) {
+				end := lineEnd(code, t.pos)
+				for _, u := range line {
+					if (u.kind == tokComment) || ((u.kind == tokPunct) && (code[u.pos] == '{')) {
+						end = u.pos
+						break
+					}
+				}
+				end = t.pos + len(strings.TrimRight(code[t.pos:end], " \t"))
+				spans = append(spans, [2]int{t.pos, end})
+			}
+		}
+		if t.kind == tokPunct {
+			switch code[t.pos] {
+			case '{':
+				depth++
+			case '}':
+				if depth > 0 {
+					depth--
+				}
+			}
+		}
+	}
+
+	prev := 0
+	for _, s := range spans {
+		dst.WriteString(markdown.EscapeHTML(code[prev:s[0]]))
+		dst.WriteString(`<span class="decl">`)
+		dst.WriteString(markdown.EscapeHTML(code[s[0]:s[1]]))
+		dst.WriteString(`</span>`)
+		prev = s[1]
+	}
+	dst.WriteString(markdown.EscapeHTML(code[prev:]))
+	return true
+}
+
+// declFuncs returns, for each supported language, whether a line's tokens
+// (which start at column 0, outside of any braces) start a function or type
+// declaration.
+var declFuncs = map[string]func(code string, line []token) bool{
+	"c":     isCDecl,
+	"cpp":   isCDecl,
+	"go":    isGoDecl,
+	"wuffs": isWuffsDecl,
+}
+
+func isGoDecl(code string, line []token) bool {
+	switch line[0].text(code) {
+	case "func", "type":
+		return true
+	}
+	return false
+}
+
+func isWuffsDecl(code string, line []token) bool {
+	switch line[0].text(code) {
+	case "pub", "pri":
+		return true
+	}
+	return false
+}
+
+// isCDecl follows the "/^[a-zA-Z].*/" rule from colorful-text.md, which works
+// for complete, clang-format'ed files. Blog posts also contain fragments, so
+// it also rules out statements: lines starting with keywords like "if" or
+// "return", assignments, member accesses and labels.
+func isCDecl(code string, line []token) bool {
+	first := line[0].text(code)
+	if cStatementKeywords[first] {
+		return false
+	}
+	for i, t := range line {
+		if t.kind != tokPunct {
+			continue
+		}
+		switch p := t.text(code); p {
+		case "(", "{", ";":
+			return (p != ";") || (i > 1)
+		case ".", "->", "=", "+=", "-=", "<<", ">>", "++", "--":
+			return false
+		case ":":
+			if i == 1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+var cStatementKeywords = map[string]bool{
+	"break": true, "case": true, "catch": true, "co_await": true, "co_return": true,
+	"co_yield": true, "continue": true, "default": true, "delete": true, "do": true,
+	"else": true, "for": true, "goto": true, "if": true, "namespace": true,
+	"private": true, "protected": true, "public": true, "return": true,
+	"sizeof": true, "static_assert": true, "switch": true, "throw": true,
+	"try": true, "using": true, "while": true,
+}
@@ -0,0 +1,159 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package highlight
+
+import (
+	"strings"
+)
+
+type tokKind uint8
+
+const (
+	tokComment tokKind = iota
+	tokIdent
+	tokNewline
+	tokNumber
+	tokPreprocessor
+	tokPunct
+	tokString
+)
+
+// token is the code[pos:end] substring. Spaces and tabs are not tokens.
+type token struct {
+	kind tokKind
+	pos  int
+	end  int
+}
+
+func (t token) text(code string) string {
+	return code[t.pos:t.end]
+}
+
+// punctuation lists the multi-byte punctuation tokens. Longer ones come
+// first. Any other non-space, non-identifier byte is a single-byte token.
+var punctuation = []string{
+	"<<=", ">>=", "...",
+	"->", "::", "<<", ">>", "++", "--", "+=", "-=", "*=", "/=", "==", "!=",
+	"<=", ">=", "&&", "||", ":=",
+}
+
+// lex splits code into tokens. It is forgiving: code is often just a fragment
+// and unterminated comments or strings simply run to the end.
+func lex(lang string, code string) (toks []token) {
+	atLineStart := true
+	for i := 0; i < len(code); {
+		c := code[i]
+		start := i
+		kind := tokPunct
+
+		switch {
+		case (c == ' ') || (c == '\t') || (c == '\r'):
+			i++
+			continue
+
+		case c == '\n':
+			kind, i = tokNewline, i+1
+
+		case strings.HasPrefix(code[i:], "//"):
+			kind, i = tokComment, lineEnd(code, i)
+
+		case strings.HasPrefix(code[i:], "/*"):
+			kind = tokComment
+			if j := strings.Index(code[i+2:], "*/"); j >= 0 {
+				i += 2 + j + 2
+			} else {
+				i = len(code)
+			}
+
+		case (c == '#') && atLineStart && ((lang == "c") || (lang == "cpp")):
+			// A preprocessor directive runs to the end of the line, including
+			// any backslash-continued lines.
+			kind = tokPreprocessor
+			for {
+				i = lineEnd(code, i)
+				if (code[i-1] != '\\') || (i == len(code)) {
+					break
+				}
+				i++
+			}
+
+		case (c == '"') || (c == '\''):
+			kind, i = tokString, quotedEnd(code, i, c)
+
+		case (c == '`') && (lang == "go"):
+			kind = tokString
+			if j := strings.IndexByte(code[i+1:], '`'); j >= 0 {
+				i += 1 + j + 1
+			} else {
+				i = len(code)
+			}
+
+		case isIdentStart(c):
+			kind = tokIdent
+			for i++; (i < len(code)) && isIdentPart(code[i]); i++ {
+			}
+
+		case ('0' <= c) && (c <= '9'):
+			kind = tokNumber
+			for i++; (i < len(code)) && (isIdentPart(code[i]) || (code[i] == '.')); i++ {
+			}
+
+		default:
+			i++
+			for _, p := range punctuation {
+				if strings.HasPrefix(code[start:], p) {
+					i = start + len(p)
+					break
+				}
+			}
+		}
+
+		toks = append(toks, token{kind, start, i})
+		atLineStart = kind == tokNewline
+	}
+	return toks
+}
+
+// lineEnd returns the position of the '\n' at or after i, or len(code).
+func lineEnd(code string, i int) int {
+	if j := strings.IndexByte(code[i:], '\n'); j >= 0 {
+		return i + j
+	}
+	return len(code)
+}
+
+// quotedEnd returns the end of the string or character literal starting at
+// code[i], which is the quote character q. Literals don't span lines.
+func quotedEnd(code string, i int, q byte) int {
+	for i++; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case '\n':
+			return i
+		case q:
+			return i + 1
+		}
+	}
+	return len(code)
+}
+
+func isIdentStart(c byte) bool {
+	return (('a' <= c) && (c <= 'z')) || (('A' <= c) && (c <= 'Z')) || (c == '_') || (c >= 0x80)
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (('0' <= c) && (c <= '9'))
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package markdown
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Slug converts heading text to an anchor ID the same way that GitHub does:
+// lower-casing, removing punctuation (other than hyphens and underscores) and
+// replacing each space with a hyphen. For example, "Lempel-Ziv 77" becomes
+// "lempel-ziv-77" and "What's `co_await`?" becomes "whats-co_await".
+func Slug(text string) string {
+	sb := &strings.Builder{}
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case (r == '-') || (r == '_'):
+			sb.WriteRune(r)
+		case r == ' ':
+			sb.WriteByte('-')
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r) || unicode.IsControl(r):
+			// No-op.
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// HeadingIDs returns the anchor ID of each of doc's headings. Like GitHub, a
+// repeated slug gets a numeric suffix, so that the second and third headings
+// titled "Foo" have IDs "foo-1" and "foo-2".
+func HeadingIDs(doc *Node) map[*Node]string {
+	ids := map[*Node]string{}
+	seen := map[string]int{}
+	doc.Walk(func(n *Node) bool {
+		if n.Kind != KindHeading {
+			return n.IsBlock()
+		}
+		slug := Slug(PlainText(n))
+		id := slug
+		for {
+			if _, ok := seen[id]; !ok {
+				break
+			}
+			seen[slug]++
+			id = slug + "-" + strconv.Itoa(seen[slug])
+		}
+		seen[id] = 0
+		ids[n] = id
+		return false
+	})
+	return ids
+}
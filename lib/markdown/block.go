@@ -0,0 +1,671 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package markdown
+
+import (
+	"strings"
+)
+
+// line is one line of source text, without its trailing "\n". Container
+// blocks (block quotes and list items) strip their markers and indentation
+// before passing their lines on to be parsed recursively, but num is always
+// the line number in the original source.
+type line struct {
+	s   string
+	num int
+}
+
+// Parse parses src as a Markdown document.
+func Parse(src []byte) *Node {
+	s := strings.ReplaceAll(string(src), "\r\n", "\n")
+	s = strings.TrimSuffix(s, "\n")
+	ss := strings.Split(s, "\n")
+	lines := make([]line, len(ss))
+	for i, x := range ss {
+		lines[i] = line{s: x, num: i + 1}
+	}
+	children, _ := parseBlocks(lines)
+	return &Node{
+		Kind:     KindDocument,
+		Children: children,
+		Line:     1,
+	}
+}
+
+// parseBlocks parses a sequence of lines as a sequence of blocks. The bool
+// returned is whether any blank line separated two of those blocks, which is
+// what makes a list item loose instead of tight.
+func parseBlocks(lines []line) (blocks []*Node, blankBetween bool) {
+	sawBlank := false
+	for i := 0; i < len(lines); {
+		l := lines[i]
+		if isBlank(l.s) {
+			sawBlank = true
+			i++
+			continue
+		}
+		if sawBlank && (len(blocks) > 0) {
+			blankBetween = true
+		}
+		sawBlank = false
+
+		n, j := parseBlock(lines, i)
+		blocks = append(blocks, n)
+		i = j
+	}
+	return blocks, blankBetween
+}
+
+// parseBlock parses the block starting at lines[i], which is not blank. It
+// returns that block and the index of the line after it.
+func parseBlock(lines []line, i int) (*Node, int) {
+	l := lines[i]
+	if indentation(l.s) >= 4 {
+		return parseIndentedCodeBlock(lines, i)
+	}
+	t := strings.TrimLeft(l.s, " ")
+
+	if c, n := fenceRun(t); n > 0 {
+		return parseFencedCodeBlock(lines, i, c, n, len(l.s)-len(t))
+	}
+	if level, text, ok := atxHeading(t); ok {
+		return &Node{
+			Kind:     KindHeading,
+			Level:    level,
+			Line:     l.num,
+			Children: parseInline(text, []int{l.num}),
+		}, i + 1
+	}
+	if isThematicBreak(t) {
+		return &Node{Kind: KindThematicBreak, Line: l.num}, i + 1
+	}
+	if t[0] == '>' {
+		return parseBlockQuote(lines, i)
+	}
+	if _, ok := listMarker(l.s); ok {
+		return parseList(lines, i)
+	}
+	if isHTMLBlockStart(t) {
+		j := i + 1
+		for (j < len(lines)) && !isBlank(lines[j].s) {
+			j++
+		}
+		return &Node{
+			Kind:    KindHTMLBlock,
+			Line:    l.num,
+			Literal: joinLines(lines[i:j], 0),
+		}, j
+	}
+	if (i+1 < len(lines)) && strings.Contains(t, "|") {
+		if aligns := tableDelimiterRow(lines[i+1].s); aligns != nil {
+			if header := splitTableRow(t); len(header) == len(aligns) {
+				return parseTable(lines, i, aligns)
+			}
+		}
+	}
+	return parseParagraph(lines, i)
+}
+
+func parseIndentedCodeBlock(lines []line, i int) (*Node, int) {
+	j := i
+	for (j < len(lines)) && (isBlank(lines[j].s) || (indentation(lines[j].s) >= 4)) {
+		j++
+	}
+	for (j > i) && isBlank(lines[j-1].s) {
+		j--
+	}
+	sb := &strings.Builder{}
+	for _, l := range lines[i:j] {
+		sb.WriteString(stripColumns(l.s, 4))
+		sb.WriteByte('\n')
+	}
+	return &Node{
+		Kind:    KindCodeBlock,
+		Line:    lines[i].num,
+		Literal: sb.String(),
+	}, j
+}
+
+func parseFencedCodeBlock(lines []line, i int, c byte, n int, indent int) (*Node, int) {
+	t := strings.TrimLeft(lines[i].s, " ")
+	info := strings.TrimSpace(t[n:])
+	sb := &strings.Builder{}
+	j := i + 1
+	for ; j < len(lines); j++ {
+		s := lines[j].s
+		if (indentation(s) < 4) && closesFence(strings.TrimLeft(s, " "), c, n) {
+			j++
+			break
+		}
+		sb.WriteString(stripColumns(s, indent))
+		sb.WriteByte('\n')
+	}
+	return &Node{
+		Kind:    KindCodeBlock,
+		Line:    lines[i].num,
+		Info:    info,
+		Literal: sb.String(),
+	}, j
+}
+
+func parseBlockQuote(lines []line, i int) (*Node, int) {
+	inner := []line(nil)
+	j := i
+	for ; j < len(lines); j++ {
+		l := lines[j]
+		t := strings.TrimLeft(l.s, " ")
+		if (indentation(l.s) < 4) && (len(t) > 0) && (t[0] == '>') {
+			t = t[1:]
+			if (len(t) > 0) && (t[0] == ' ') {
+				t = t[1:]
+			}
+			inner = append(inner, line{s: t, num: l.num})
+			continue
+		}
+		// A lazy continuation line continues a paragraph without repeating
+		// the '>' marker.
+		if isBlank(l.s) || (len(inner) == 0) || isBlank(inner[len(inner)-1].s) ||
+			interruptsParagraph(l.s) {
+			break
+		}
+		inner = append(inner, l)
+	}
+	children, _ := parseBlocks(inner)
+	return &Node{
+		Kind:     KindBlockQuote,
+		Line:     lines[i].num,
+		Children: children,
+	}, j
+}
+
+func parseList(lines []line, i int) (*Node, int) {
+	first, _ := listMarker(lines[i].s)
+	list := &Node{
+		Kind:    KindList,
+		Line:    lines[i].num,
+		Ordered: first.ordered,
+		Start:   first.number,
+		Tight:   true,
+	}
+
+	for i < len(lines) {
+		m, ok := listMarker(lines[i].s)
+		if !ok || (m.ordered != first.ordered) || (m.delim != first.delim) {
+			break
+		}
+
+		inner := []line{{s: m.rest, num: lines[i].num}}
+		j := i + 1
+		for ; j < len(lines); j++ {
+			l := lines[j]
+			if isBlank(l.s) {
+				inner = append(inner, line{s: "", num: l.num})
+			} else if indentation(l.s) >= m.contentIndent {
+				inner = append(inner, line{s: stripColumns(l.s, m.contentIndent), num: l.num})
+			} else if !isBlank(inner[len(inner)-1].s) && !interruptsParagraph(l.s) &&
+				!startsListItem(l.s) && !inFence(inner) {
+				inner = append(inner, line{s: strings.TrimLeft(l.s, " "), num: l.num})
+			} else {
+				break
+			}
+		}
+
+		// Trailing blank lines belong between items, not inside one.
+		trailingBlanks := 0
+		for (len(inner) > 1) && isBlank(inner[len(inner)-1].s) {
+			inner = inner[:len(inner)-1]
+			trailingBlanks++
+		}
+		children, blankBetween := parseBlocks(inner)
+		if blankBetween {
+			list.Tight = false
+		}
+		list.Children = append(list.Children, &Node{
+			Kind:     KindListItem,
+			Line:     lines[i].num,
+			Children: children,
+		})
+
+		i = j
+		if trailingBlanks > 0 {
+			if m, ok := listMarker(safeLine(lines, i)); ok &&
+				(m.ordered == first.ordered) && (m.delim == first.delim) {
+				list.Tight = false
+			} else {
+				// Give the blank lines back, so that parseBlocks sees them.
+				i -= trailingBlanks
+				break
+			}
+		}
+	}
+	return list, i
+}
+
+func parseTable(lines []line, i int, aligns []Alignment) (*Node, int) {
+	table := &Node{
+		Kind: KindTable,
+		Line: lines[i].num,
+	}
+	table.Children = append(table.Children, tableRow(lines[i], aligns, true))
+	j := i + 2
+	for ; j < len(lines); j++ {
+		l := lines[j]
+		if isBlank(l.s) || interruptsParagraph(l.s) {
+			break
+		}
+		table.Children = append(table.Children, tableRow(l, aligns, false))
+	}
+	return table, j
+}
+
+func tableRow(l line, aligns []Alignment, header bool) *Node {
+	row := &Node{
+		Kind: KindTableRow,
+		Line: l.num,
+	}
+	cells := splitTableRow(strings.TrimSpace(l.s))
+	for k, align := range aligns {
+		text := ""
+		if k < len(cells) {
+			text = cells[k]
+		}
+		row.Children = append(row.Children, &Node{
+			Kind:     KindTableCell,
+			Line:     l.num,
+			Align:    align,
+			Header:   header,
+			Children: parseInline(text, []int{l.num}),
+		})
+	}
+	return row
+}
+
+func parseParagraph(lines []line, i int) (*Node, int) {
+	j := i + 1
+	for ; j < len(lines); j++ {
+		s := lines[j].s
+		if isBlank(s) {
+			break
+		}
+		if level := setextUnderline(s); level > 0 {
+			return &Node{
+				Kind:     KindHeading,
+				Level:    level,
+				Line:     lines[i].num,
+				Children: parseInlineLines(lines[i:j]),
+			}, j + 1
+		}
+		if interruptsParagraph(s) {
+			break
+		}
+	}
+	return &Node{
+		Kind:     KindParagraph,
+		Line:     lines[i].num,
+		Children: parseInlineLines(lines[i:j]),
+	}, j
+}
+
+func parseInlineLines(lines []line) []*Node {
+	nums := make([]int, len(lines))
+	for k, l := range lines {
+		nums[k] = l.num
+	}
+	return parseInline(strings.TrimSpace(joinLines(lines, -1)), nums)
+}
+
+// joinLines joins the lines' text with "\n" separators. If trimLeft is
+// negative, each line's leading whitespace is removed.
+func joinLines(lines []line, trimLeft int) string {
+	sb := &strings.Builder{}
+	for k, l := range lines {
+		if k > 0 {
+			sb.WriteByte('\n')
+		}
+		if trimLeft < 0 {
+			sb.WriteString(strings.TrimLeft(l.s, " \t"))
+		} else {
+			sb.WriteString(l.s)
+		}
+	}
+	return sb.String()
+}
+
+// interruptsParagraph returns whether s, a line that directly follows a
+// paragraph line, starts a new block instead of continuing that paragraph.
+func interruptsParagraph(s string) bool {
+	if indentation(s) >= 4 {
+		return false
+	}
+	t := strings.TrimLeft(s, " ")
+	if t == "" {
+		return true
+	}
+	if _, n := fenceRun(t); n > 0 {
+		return true
+	}
+	if _, _, ok := atxHeading(t); ok {
+		return true
+	}
+	if isThematicBreak(t) || (t[0] == '>') || isHTMLBlockStart(t) {
+		return true
+	}
+	if m, ok := listMarker(s); ok && !isBlank(m.rest) && (!m.ordered || (m.number == 1)) {
+		return true
+	}
+	return false
+}
+
+func startsListItem(s string) bool {
+	_, ok := listMarker(s)
+	return ok
+}
+
+// inFence returns whether lines ends inside an unclosed fenced code block.
+func inFence(lines []line) bool {
+	c, n := byte(0), 0
+	for _, l := range lines {
+		t := strings.TrimLeft(l.s, " ")
+		if n == 0 {
+			c, n = fenceRun(t)
+		} else if closesFence(t, c, n) {
+			n = 0
+		}
+	}
+	return n > 0
+}
+
+func safeLine(lines []line, i int) string {
+	if i < len(lines) {
+		return lines[i].s
+	}
+	return ""
+}
+
+func isBlank(s string) bool {
+	return strings.TrimLeft(s, " \t") == ""
+}
+
+// indentation returns the number of columns of leading whitespace in s, with
+// tab stops every 4 columns.
+func indentation(s string) int {
+	col := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ' ':
+			col++
+		case '\t':
+			col += 4 - (col % 4)
+		default:
+			return col
+		}
+	}
+	return col
+}
+
+// stripColumns removes up to n columns of leading whitespace from s. A tab
+// that straddles the n'th column is replaced by the spaces that remain.
+func stripColumns(s string, n int) string {
+	col := 0
+	for i := 0; i < len(s); i++ {
+		if col >= n {
+			return s[i:]
+		}
+		switch s[i] {
+		case ' ':
+			col++
+		case '\t':
+			col += 4 - (col % 4)
+			if col > n {
+				return strings.Repeat(" ", col-n) + s[i+1:]
+			}
+		default:
+			return s[i:]
+		}
+	}
+	return ""
+}
+
+// fenceRun returns the fence character and run length if t (a line with its
+// leading spaces removed) opens a fenced code block.
+func fenceRun(t string) (c byte, n int) {
+	if (len(t) < 3) || ((t[0] != '`') && (t[0] != '~')) {
+		return 0, 0
+	}
+	c = t[0]
+	for (n < len(t)) && (t[n] == c) {
+		n++
+	}
+	if (n < 3) || ((c == '`') && strings.IndexByte(t[n:], '`') >= 0) {
+		return 0, 0
+	}
+	return c, n
+}
+
+func closesFence(t string, c byte, n int) bool {
+	k := 0
+	for (k < len(t)) && (t[k] == c) {
+		k++
+	}
+	return (k >= n) && isBlank(t[k:])
+}
+
+func atxHeading(t string) (level int, text string, ok bool) {
+	for (level < len(t)) && (t[level] == '#') {
+		level++
+	}
+	if (level == 0) || (level > 6) {
+		return 0, "", false
+	}
+	text = t[level:]
+	if (text != "") && (text[0] != ' ') && (text[0] != '\t') {
+		return 0, "", false
+	}
+	text = strings.TrimSpace(text)
+	// Remove an optional closing sequence of '#'s.
+	if k := strings.TrimRight(text, "#"); len(k) < len(text) {
+		if k == "" {
+			text = ""
+		} else if (k[len(k)-1] == ' ') || (k[len(k)-1] == '\t') {
+			text = strings.TrimSpace(k)
+		}
+	}
+	return level, text, true
+}
+
+func isThematicBreak(t string) bool {
+	if t == "" {
+		return false
+	}
+	c, n := t[0], 0
+	if (c != '-') && (c != '*') && (c != '_') {
+		return false
+	}
+	for i := 0; i < len(t); i++ {
+		switch t[i] {
+		case c:
+			n++
+		case ' ', '\t':
+		default:
+			return false
+		}
+	}
+	return n >= 3
+}
+
+func setextUnderline(s string) (level int) {
+	if indentation(s) >= 4 {
+		return 0
+	}
+	t := strings.TrimSpace(s)
+	if t == "" {
+		return 0
+	} else if strings.Trim(t, "=") == "" {
+		return 1
+	} else if strings.Trim(t, "-") == "" {
+		return 2
+	}
+	return 0
+}
+
+func isHTMLBlockStart(t string) bool {
+	if (len(t) < 2) || (t[0] != '<') {
+		return false
+	}
+	if strings.HasPrefix(t, "<!--") {
+		return true
+	}
+	u := t[1:]
+	if u[0] == '/' {
+		u = u[1:]
+	}
+	k := 0
+	for (k < len(u)) && isASCIIAlnum(u[k]) {
+		k++
+	}
+	if (k == 0) || !isASCIILetter(u[0]) {
+		return false
+	}
+	return htmlBlockTags[strings.ToLower(u[:k])] &&
+		((k == len(u)) || (u[k] == ' ') || (u[k] == '>') || (u[k] == '/'))
+}
+
+// htmlBlockTags are the HTML elements that can start an HTML block.
+var htmlBlockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"details": true, "div": true, "dl": true, "figcaption": true,
+	"figure": true, "footer": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+	"iframe": true, "img": true, "nav": true, "ol": true, "p": true,
+	"picture": true, "pre": true, "section": true, "summary": true,
+	"table": true, "ul": true, "video": true,
+}
+
+type listMarkerInfo struct {
+	ordered bool
+	number  int
+	delim   byte
+
+	// contentIndent is the column that the item's content starts at.
+	contentIndent int
+
+	// rest is the text on the marker's line after the marker.
+	rest string
+}
+
+func listMarker(s string) (m listMarkerInfo, ok bool) {
+	ind := indentation(s)
+	if ind >= 4 {
+		return m, false
+	}
+	t := strings.TrimLeft(s, " ")
+	k := 0
+	if (len(t) > 0) && ((t[0] == '-') || (t[0] == '*') || (t[0] == '+')) {
+		m.delim = t[0]
+		k = 1
+	} else {
+		for (k < len(t)) && (k < 9) && ('0' <= t[k]) && (t[k] <= '9') {
+			m.number = 10*m.number + int(t[k]-'0')
+			k++
+		}
+		if (k == 0) || (k == len(t)) || ((t[k] != '.') && (t[k] != ')')) {
+			return m, false
+		}
+		m.ordered = true
+		m.delim = t[k]
+		k++
+	}
+
+	rest := t[k:]
+	if rest == "" {
+		m.contentIndent = ind + k + 1
+		return m, true
+	} else if (rest[0] != ' ') && (rest[0] != '\t') {
+		return m, false
+	}
+	spaces := indentation(rest)
+	if isBlank(rest) || (spaces > 4) {
+		spaces = 1
+	}
+	m.contentIndent = ind + k + spaces
+	m.rest = stripColumns(rest, spaces)
+	if isBlank(rest) {
+		m.rest = ""
+	}
+	if !m.ordered && isThematicBreak(t) {
+		return m, false
+	}
+	return m, true
+}
+
+// tableDelimiterRow parses a GFM table's second line, such as "|---|:--:|",
+// returning nil if s is not a delimiter row.
+func tableDelimiterRow(s string) []Alignment {
+	if indentation(s) >= 4 {
+		return nil
+	}
+	t := strings.TrimSpace(s)
+	if !strings.Contains(t, "-") || strings.Trim(t, "|:- \t") != "" {
+		return nil
+	}
+	cells := splitTableRow(t)
+	aligns := make([]Alignment, len(cells))
+	for k, c := range cells {
+		left, right := strings.HasPrefix(c, ":"), strings.HasSuffix(c, ":")
+		if strings.Trim(c, ":") == "" || strings.Trim(c, ":-") != "" {
+			return nil
+		}
+		switch {
+		case left && right:
+			aligns[k] = AlignCenter
+		case left:
+			aligns[k] = AlignLeft
+		case right:
+			aligns[k] = AlignRight
+		}
+	}
+	return aligns
+}
+
+// splitTableRow splits t on its unescaped '|' characters, dropping any
+// leading and trailing '|'. Each cell's text is trimmed of whitespace.
+func splitTableRow(t string) (cells []string) {
+	t = strings.TrimPrefix(t, "|")
+	if strings.HasSuffix(t, "|") && !strings.HasSuffix(t, "\\|") {
+		t = t[:len(t)-1]
+	}
+	start := 0
+	for i := 0; i < len(t); i++ {
+		if t[i] == '\\' {
+			i++
+		} else if t[i] == '|' {
+			cells = append(cells, strings.TrimSpace(t[start:i]))
+			start = i + 1
+		}
+	}
+	cells = append(cells, strings.TrimSpace(t[start:]))
+	for k, c := range cells {
+		cells[k] = strings.ReplaceAll(c, "\\|", "|")
+	}
+	return cells
+}
+
+func isASCIILetter(c byte) bool {
+	return (('a' <= c) && (c <= 'z')) || (('A' <= c) && (c <= 'Z'))
+}
+
+func isASCIIAlnum(c byte) bool {
+	return isASCIILetter(c) || (('0' <= c) && (c <= '9'))
+}
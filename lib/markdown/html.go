@@ -0,0 +1,268 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package markdown
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// Renderer renders a Markdown syntax tree as HTML.
+//
+// The zero value is usable, and renders the tree verbatim.
+type Renderer struct {
+	// RewriteURL, if non-nil, maps each link and image destination to the
+	// URL that the HTML should use.
+	RewriteURL func(url string) string
+
+	// HighlightCode, if non-nil, is called for each code block, with its
+	// language (which may be empty) and text. It either writes the block's
+	// HTML (which must escape the text) to dst and returns true, or writes
+	// nothing and returns false, in which case the text is written plainly.
+	HighlightCode func(dst *bytes.Buffer, lang string, code string) bool
+
+	// HeadingIDs, if non-nil, gives each heading's id attribute, so that
+	// "#fragment" links to it work. HeadingIDs(doc) returns IDs that are
+	// compatible with GitHub's.
+	HeadingIDs map[*Node]string
+
+	// XHTML is whether to write void elements like <br /> as self-closing
+	// tags, so that the HTML is also well-formed XML, as EPUB requires. Raw
+	// HTML blocks are written as is, either way.
+	XHTML bool
+}
+
+// Render writes the HTML for n to dst.
+func (r *Renderer) Render(dst *bytes.Buffer, n *Node) {
+	switch n.Kind {
+	case KindDocument:
+		r.RenderChildren(dst, n)
+
+	case KindBlockQuote:
+		dst.WriteString("<blockquote>\n")
+		r.RenderChildren(dst, n)
+		dst.WriteString("</blockquote>\n")
+
+	case KindCodeBlock:
+		dst.WriteString("<pre><code")
+		if lang := n.Language(); lang != "" {
+			dst.WriteString(` class="language-`)
+			dst.WriteString(EscapeHTML(lang))
+			dst.WriteString(`"`)
+		}
+		dst.WriteString(">")
+		if (r.HighlightCode == nil) || !r.HighlightCode(dst, n.Language(), n.Literal) {
+			dst.WriteString(EscapeHTML(n.Literal))
+		}
+		dst.WriteString("</code></pre>\n")
+
+	case KindHTMLBlock:
+		dst.WriteString(n.Literal)
+		dst.WriteString("\n")
+
+	case KindHeading:
+		h := "h" + strconv.Itoa(n.Level)
+		dst.WriteString("<" + h)
+		if id := r.HeadingIDs[n]; id != "" {
+			dst.WriteString(` id="`)
+			dst.WriteString(EscapeHTML(id))
+			dst.WriteString(`"`)
+		}
+		dst.WriteString(">")
+		r.RenderChildren(dst, n)
+		dst.WriteString("</" + h + ">\n")
+
+	case KindList:
+		tag := "ul"
+		if n.Ordered {
+			tag = "ol"
+		}
+		dst.WriteString("<" + tag)
+		if n.Ordered && (n.Start != 1) {
+			dst.WriteString(` start="` + strconv.Itoa(n.Start) + `"`)
+		}
+		dst.WriteString(">\n")
+		for _, item := range n.Children {
+			r.renderListItem(dst, item, n.Tight)
+		}
+		dst.WriteString("</" + tag + ">\n")
+
+	case KindListItem:
+		r.renderListItem(dst, n, false)
+
+	case KindParagraph:
+		dst.WriteString("<p>")
+		r.RenderChildren(dst, n)
+		dst.WriteString("</p>\n")
+
+	case KindTable:
+		dst.WriteString("<table>\n")
+		for i, row := range n.Children {
+			if i == 0 {
+				dst.WriteString("<thead>\n")
+			} else if i == 1 {
+				dst.WriteString("<tbody>\n")
+			}
+			r.Render(dst, row)
+			if i == 0 {
+				dst.WriteString("</thead>\n")
+			}
+		}
+		if len(n.Children) > 1 {
+			dst.WriteString("</tbody>\n")
+		}
+		dst.WriteString("</table>\n")
+
+	case KindTableRow:
+		dst.WriteString("<tr>\n")
+		r.RenderChildren(dst, n)
+		dst.WriteString("</tr>\n")
+
+	case KindTableCell:
+		tag := "td"
+		if n.Header {
+			tag = "th"
+		}
+		dst.WriteString("<" + tag)
+		switch n.Align {
+		case AlignLeft:
+			dst.WriteString(` align="left"`)
+		case AlignCenter:
+			dst.WriteString(` align="center"`)
+		case AlignRight:
+			dst.WriteString(` align="right"`)
+		}
+		dst.WriteString(">")
+		r.RenderChildren(dst, n)
+		dst.WriteString("</" + tag + ">\n")
+
+	case KindThematicBreak:
+		dst.WriteString("<hr" + r.voidEnd() + "\n")
+
+	case KindCodeSpan:
+		dst.WriteString("<code>")
+		dst.WriteString(EscapeHTML(n.Literal))
+		dst.WriteString("</code>")
+
+	case KindEmphasis:
+		dst.WriteString("<em>")
+		r.RenderChildren(dst, n)
+		dst.WriteString("</em>")
+
+	case KindImage:
+		dst.WriteString(`<img src="`)
+		dst.WriteString(EscapeHTML(r.url(n.Destination)))
+		dst.WriteString(`" alt="`)
+		dst.WriteString(EscapeHTML(PlainText(n)))
+		dst.WriteString(`"`)
+		if n.Title != "" {
+			dst.WriteString(` title="`)
+			dst.WriteString(EscapeHTML(n.Title))
+			dst.WriteString(`"`)
+		}
+		dst.WriteString(r.voidEnd())
+
+	case KindLineBreak:
+		dst.WriteString("<br" + r.voidEnd() + "\n")
+
+	case KindLink:
+		dst.WriteString(`<a href="`)
+		dst.WriteString(EscapeHTML(r.url(n.Destination)))
+		dst.WriteString(`"`)
+		if n.Title != "" {
+			dst.WriteString(` title="`)
+			dst.WriteString(EscapeHTML(n.Title))
+			dst.WriteString(`"`)
+		}
+		dst.WriteString(">")
+		r.RenderChildren(dst, n)
+		dst.WriteString("</a>")
+
+	case KindSoftBreak:
+		dst.WriteString("\n")
+
+	case KindStrong:
+		dst.WriteString("<strong>")
+		r.RenderChildren(dst, n)
+		dst.WriteString("</strong>")
+
+	case KindText:
+		dst.WriteString(EscapeHTML(n.Literal))
+	}
+}
+
+// RenderChildren writes the HTML for n's children to dst. For an inline
+// container, such as the result of ParseInline, this omits the enclosing
+// <p></p> tags.
+func (r *Renderer) RenderChildren(dst *bytes.Buffer, n *Node) {
+	for _, c := range n.Children {
+		r.Render(dst, c)
+	}
+}
+
+func (r *Renderer) renderListItem(dst *bytes.Buffer, n *Node, tight bool) {
+	dst.WriteString("<li>")
+	for i, c := range n.Children {
+		if tight && (c.Kind == KindParagraph) {
+			r.RenderChildren(dst, c)
+			if i+1 < len(n.Children) {
+				dst.WriteString("\n")
+			}
+			continue
+		}
+		if i == 0 {
+			dst.WriteString("\n")
+		}
+		r.Render(dst, c)
+	}
+	dst.WriteString("</li>\n")
+}
+
+// voidEnd returns how to end a void element's tag.
+func (r *Renderer) voidEnd() string {
+	if r.XHTML {
+		return " />"
+	}
+	return ">"
+}
+
+func (r *Renderer) url(u string) string {
+	if r.RewriteURL != nil {
+		return r.RewriteURL(u)
+	}
+	return u
+}
+
+// Language returns the first word of a KindCodeBlock's info string.
+func (n *Node) Language() string {
+	if i := strings.IndexAny(n.Info, " \t{"); i >= 0 {
+		return n.Info[:i]
+	}
+	return n.Info
+}
+
+// EscapeHTML escapes the characters that are special in HTML text and
+// attribute values. Unlike html.EscapeString, it leaves apostrophes alone.
+func EscapeHTML(s string) string {
+	return htmlEscaper.Replace(s)
+}
+
+var htmlEscaper = strings.NewReplacer(
+	`&`, "&amp;",
+	`<`, "&lt;",
+	`>`, "&gt;",
+	`"`, "&quot;",
+)
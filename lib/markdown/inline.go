@@ -0,0 +1,642 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package markdown
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ParseInline parses src as a single paragraph's worth of inline Markdown,
+// such as a blog post title. The returned node's Kind is KindParagraph.
+func ParseInline(src string) *Node {
+	return &Node{
+		Kind:     KindParagraph,
+		Line:     1,
+		Children: parseInline(strings.TrimSpace(src), []int{1}),
+	}
+}
+
+// delimiter is a run of '*' or '_' characters that might open or close
+// emphasis, or a '[' or "![" that might open a link or image.
+type delimiter struct {
+	node     *Node
+	c        byte
+	count    int
+	origLen  int
+	canOpen  bool
+	canClose bool
+
+	// active is false for a '[' that can no longer open a link, because
+	// links cannot contain other links.
+	active bool
+}
+
+type inlineParser struct {
+	src   string
+	pos   int
+	nodes []*Node
+	delim []*delimiter
+
+	// lineNums[i] is the source line number of the i'th line of src.
+	lineNums []int
+}
+
+// parseInline parses src, whose i'th line came from source line lineNums[i].
+func parseInline(src string, lineNums []int) []*Node {
+	p := &inlineParser{
+		src:      src,
+		lineNums: lineNums,
+	}
+	p.parse()
+	p.processEmphasis(-1)
+	return mergeText(p.nodes)
+}
+
+func (p *inlineParser) lineAt(pos int) int {
+	k := strings.Count(p.src[:pos], "\n")
+	if k >= len(p.lineNums) {
+		k = len(p.lineNums) - 1
+	}
+	return p.lineNums[k]
+}
+
+func (p *inlineParser) text(s string, pos int) *Node {
+	n := &Node{Kind: KindText, Literal: s, Line: p.lineAt(pos)}
+	p.nodes = append(p.nodes, n)
+	return n
+}
+
+func (p *inlineParser) parse() {
+	textStart := 0
+	flush := func() {
+		if textStart < p.pos {
+			p.text(p.src[textStart:p.pos], textStart)
+		}
+	}
+
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch c {
+		case '\\', '`', '*', '_', '[', '!', ']', '<', '&', '\n', 'h':
+		default:
+			p.pos++
+			continue
+		}
+
+		flush()
+		start := p.pos
+		if !p.parseSpecial(c) {
+			p.pos = start + 1
+			textStart = start
+			continue
+		}
+		textStart = p.pos
+	}
+	flush()
+}
+
+// parseSpecial parses the construct starting at p.pos with the byte c. It
+// returns false (leaving p.pos unchanged) if it is plain text after all.
+func (p *inlineParser) parseSpecial(c byte) bool {
+	start := p.pos
+	switch c {
+	case '\\':
+		if start+1 < len(p.src) {
+			if d := p.src[start+1]; d == '\n' {
+				p.nodes = append(p.nodes, &Node{Kind: KindLineBreak, Line: p.lineAt(start)})
+				p.pos += 2
+				p.skipLeadingSpaces()
+				return true
+			} else if isASCIIPunct(d) {
+				p.text(p.src[start+1:start+2], start)
+				p.pos += 2
+				return true
+			}
+		}
+
+	case '`':
+		return p.parseCodeSpan()
+
+	case '*', '_':
+		p.parseDelimiterRun(c)
+		return true
+
+	case '[':
+		n := p.text("[", start)
+		p.delim = append(p.delim, &delimiter{node: n, c: '[', active: true})
+		p.pos++
+		return true
+
+	case '!':
+		if (start+1 < len(p.src)) && (p.src[start+1] == '[') {
+			n := p.text("![", start)
+			p.delim = append(p.delim, &delimiter{node: n, c: '!', active: true})
+			p.pos += 2
+			return true
+		}
+
+	case ']':
+		return p.parseCloseBracket()
+
+	case '<':
+		return p.parseAutolink()
+
+	case '&':
+		if end := entityEnd(p.src[start:]); end > 0 {
+			p.text(html.UnescapeString(p.src[start:start+end]), start)
+			p.pos += end
+			return true
+		}
+
+	case '\n':
+		// Trailing spaces were already flushed as text. Two or more of them
+		// make a hard line break.
+		kind := KindSoftBreak
+		if k := len(p.nodes) - 1; (k >= 0) && (p.nodes[k].Kind == KindText) &&
+			(p.nodes[k].Literal != "") {
+			lit := p.nodes[k].Literal
+			trimmed := strings.TrimRight(lit, " ")
+			if len(lit)-len(trimmed) >= 2 {
+				kind = KindLineBreak
+			}
+			p.nodes[k].Literal = trimmed
+		}
+		p.nodes = append(p.nodes, &Node{Kind: kind, Line: p.lineAt(start)})
+		p.pos++
+		p.skipLeadingSpaces()
+		return true
+
+	case 'h':
+		return p.parseBareURL()
+	}
+	return false
+}
+
+func (p *inlineParser) skipLeadingSpaces() {
+	for (p.pos < len(p.src)) && (p.src[p.pos] == ' ') {
+		p.pos++
+	}
+}
+
+func (p *inlineParser) parseCodeSpan() bool {
+	start := p.pos
+	n := 0
+	for (start+n < len(p.src)) && (p.src[start+n] == '`') {
+		n++
+	}
+	for i := start + n; i < len(p.src); {
+		if p.src[i] != '`' {
+			i++
+			continue
+		}
+		m := 0
+		for (i+m < len(p.src)) && (p.src[i+m] == '`') {
+			m++
+		}
+		if m != n {
+			i += m
+			continue
+		}
+		lit := strings.ReplaceAll(p.src[start+n:i], "\n", " ")
+		if (len(lit) >= 2) && (lit[0] == ' ') && (lit[len(lit)-1] == ' ') &&
+			(strings.Trim(lit, " ") != "") {
+			lit = lit[1 : len(lit)-1]
+		}
+		p.nodes = append(p.nodes, &Node{Kind: KindCodeSpan, Literal: lit, Line: p.lineAt(start)})
+		p.pos = i + m
+		return true
+	}
+	// No matching closer. The whole backtick run is literal text.
+	p.text(p.src[start:start+n], start)
+	p.pos = start + n
+	return true
+}
+
+func (p *inlineParser) parseDelimiterRun(c byte) {
+	start := p.pos
+	end := start
+	for (end < len(p.src)) && (p.src[end] == c) {
+		end++
+	}
+	before, _ := utf8.DecodeLastRuneInString(p.src[:start])
+	if start == 0 {
+		before = ' '
+	}
+	after, _ := utf8.DecodeRuneInString(p.src[end:])
+	if end == len(p.src) {
+		after = ' '
+	}
+
+	leftFlanking := !isSpace(after) &&
+		(!isPunct(after) || isSpace(before) || isPunct(before))
+	rightFlanking := !isSpace(before) &&
+		(!isPunct(before) || isSpace(after) || isPunct(after))
+
+	d := &delimiter{
+		node:    p.text(p.src[start:end], start),
+		c:       c,
+		count:   end - start,
+		origLen: end - start,
+	}
+	if c == '*' {
+		d.canOpen = leftFlanking
+		d.canClose = rightFlanking
+	} else {
+		d.canOpen = leftFlanking && (!rightFlanking || isPunct(before))
+		d.canClose = rightFlanking && (!leftFlanking || isPunct(after))
+	}
+	p.delim = append(p.delim, d)
+	p.pos = end
+}
+
+func (p *inlineParser) parseCloseBracket() bool {
+	start := p.pos
+	opener := -1
+	for k := len(p.delim) - 1; k >= 0; k-- {
+		if c := p.delim[k].c; (c == '[') || (c == '!') {
+			opener = k
+			break
+		}
+	}
+	if opener < 0 {
+		return false
+	}
+	d := p.delim[opener]
+	if !d.active {
+		p.delim = append(p.delim[:opener], p.delim[opener+1:]...)
+		return false
+	}
+
+	dest, title, end, ok := parseLinkTail(p.src, start+1)
+	if !ok {
+		p.delim = append(p.delim[:opener], p.delim[opener+1:]...)
+		return false
+	}
+
+	p.processEmphasis(opener)
+	k := indexOf(p.nodes, d.node)
+	link := &Node{
+		Kind:        KindLink,
+		Line:        d.node.Line,
+		Destination: dest,
+		Title:       title,
+		Children:    mergeText(append([]*Node(nil), p.nodes[k+1:]...)),
+	}
+	if d.c == '!' {
+		link.Kind = KindImage
+	}
+	p.nodes = append(p.nodes[:k], link)
+	p.delim = p.delim[:opener]
+	p.pos = end
+
+	// Links may not contain other links, so deactivate earlier '[' openers.
+	if link.Kind == KindLink {
+		for _, e := range p.delim {
+			if e.c == '[' {
+				e.active = false
+			}
+		}
+	}
+	return true
+}
+
+// parseLinkTail parses the "(destination "title")" part of an inline link or
+// image, starting at s[i].
+func parseLinkTail(s string, i int) (dest string, title string, end int, ok bool) {
+	if (i >= len(s)) || (s[i] != '(') {
+		return "", "", 0, false
+	}
+	i = skipSpace(s, i+1)
+
+	if (i < len(s)) && (s[i] == '<') {
+		j := i + 1
+		for (j < len(s)) && (s[j] != '>') && (s[j] != '\n') && (s[j] != '<') {
+			if s[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		if (j >= len(s)) || (s[j] != '>') {
+			return "", "", 0, false
+		}
+		dest = unescapeBackslashes(s[i+1 : j])
+		i = j + 1
+	} else {
+		j, depth := i, 0
+		for ; j < len(s); j++ {
+			c := s[j]
+			if c == '\\' {
+				// Skip the escaped byte, if there is one.
+				if (j + 1) < len(s) {
+					j++
+				}
+			} else if c == '(' {
+				depth++
+			} else if c == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			} else if (c <= ' ') || (c == 0x7F) {
+				break
+			}
+		}
+		dest = unescapeBackslashes(s[i:j])
+		i = j
+	}
+
+	j := skipSpace(s, i)
+	if (j < len(s)) && (j > i) && ((s[j] == '"') || (s[j] == '\'') || (s[j] == '(')) {
+		closer := s[j]
+		if closer == '(' {
+			closer = ')'
+		}
+		k := j + 1
+		for (k < len(s)) && (s[k] != closer) {
+			if s[k] == '\\' {
+				k++
+			}
+			k++
+		}
+		if k >= len(s) {
+			return "", "", 0, false
+		}
+		title = unescapeBackslashes(s[j+1 : k])
+		j = skipSpace(s, k+1)
+	}
+	if (j >= len(s)) || (s[j] != ')') {
+		return "", "", 0, false
+	}
+	return html.UnescapeString(dest), html.UnescapeString(title), j + 1, true
+}
+
+func (p *inlineParser) parseAutolink() bool {
+	start := p.pos
+	end := strings.IndexByte(p.src[start:], '>')
+	if end < 0 {
+		return false
+	}
+	inner := p.src[start+1 : start+end]
+	if strings.ContainsAny(inner, " \t\n<") {
+		return false
+	}
+	dest := ""
+	if i := strings.IndexByte(inner, ':'); (i >= 2) && isScheme(inner[:i]) {
+		dest = inner
+	} else if i := strings.IndexByte(inner, '@'); (i > 0) && strings.Contains(inner[i:], ".") {
+		dest = "mailto:" + inner
+	} else {
+		return false
+	}
+	p.nodes = append(p.nodes, &Node{
+		Kind:        KindLink,
+		Line:        p.lineAt(start),
+		Destination: dest,
+		Children:    []*Node{{Kind: KindText, Literal: inner, Line: p.lineAt(start)}},
+	})
+	p.pos = start + end + 1
+	return true
+}
+
+// parseBareURL parses a GitHub Flavored Markdown extended autolink: a
+// "http://" or "https://" URL that isn't inside angle brackets.
+func (p *inlineParser) parseBareURL() bool {
+	start := p.pos
+	rest := p.src[start:]
+	if !strings.HasPrefix(rest, "http://") && !strings.HasPrefix(rest, "https://") {
+		return false
+	}
+	if (start > 0) && isASCIIAlnum(p.src[start-1]) {
+		return false
+	}
+	// Links may not contain other links.
+	for _, d := range p.delim {
+		if (d.c == '[') && d.active {
+			return false
+		}
+	}
+	end := 0
+	for (end < len(rest)) && (rest[end] > ' ') && (rest[end] != '<') {
+		end++
+	}
+	u := rest[:end]
+	for len(u) > 0 {
+		c := u[len(u)-1]
+		if strings.IndexByte("?!.,:*_~'\"", c) >= 0 {
+			u = u[:len(u)-1]
+		} else if (c == ')') && (strings.Count(u, "(") < strings.Count(u, ")")) {
+			u = u[:len(u)-1]
+		} else {
+			break
+		}
+	}
+	if i := strings.IndexByte(u, ':'); len(u) <= i+3 {
+		return false
+	}
+	p.nodes = append(p.nodes, &Node{
+		Kind:        KindLink,
+		Line:        p.lineAt(start),
+		Destination: u,
+		Children:    []*Node{{Kind: KindText, Literal: u, Line: p.lineAt(start)}},
+	})
+	p.pos = start + len(u)
+	return true
+}
+
+// processEmphasis matches up the '*' and '_' delimiters above
+// p.delim[stackBottom], turning the text between them into KindEmphasis and
+// KindStrong nodes. This follows the CommonMark specification's algorithm.
+func (p *inlineParser) processEmphasis(stackBottom int) {
+	for c := stackBottom + 1; c < len(p.delim); {
+		closer := p.delim[c]
+		if ((closer.c != '*') && (closer.c != '_')) || !closer.canClose {
+			c++
+			continue
+		}
+
+		o := c - 1
+		for ; o > stackBottom; o-- {
+			opener := p.delim[o]
+			if (opener.c != closer.c) || !opener.canOpen {
+				continue
+			}
+			if (opener.canClose || closer.canOpen) &&
+				((opener.origLen+closer.origLen)%3 == 0) &&
+				((opener.origLen%3 != 0) || (closer.origLen%3 != 0)) {
+				continue
+			}
+			break
+		}
+		if o <= stackBottom {
+			if !closer.canOpen {
+				p.delim = append(p.delim[:c], p.delim[c+1:]...)
+			} else {
+				c++
+			}
+			continue
+		}
+
+		opener := p.delim[o]
+		n := 1
+		kind := KindEmphasis
+		if (opener.count >= 2) && (closer.count >= 2) {
+			n = 2
+			kind = KindStrong
+		}
+		opener.count -= n
+		closer.count -= n
+		opener.node.Literal = opener.node.Literal[n:]
+		closer.node.Literal = closer.node.Literal[n:]
+
+		i := indexOf(p.nodes, opener.node)
+		j := indexOf(p.nodes, closer.node)
+		emph := &Node{
+			Kind:     kind,
+			Line:     opener.node.Line,
+			Children: mergeText(append([]*Node(nil), p.nodes[i+1:j]...)),
+		}
+		nodes := append([]*Node(nil), p.nodes[:i+1]...)
+		nodes = append(nodes, emph)
+		p.nodes = append(nodes, p.nodes[j:]...)
+
+		// Remove the delimiters between the opener and the closer, and then
+		// the opener and closer themselves if they are used up.
+		p.delim = append(p.delim[:o+1], p.delim[c:]...)
+		c = o + 1
+		if opener.count == 0 {
+			p.removeNode(opener.node)
+			p.delim = append(p.delim[:o], p.delim[o+1:]...)
+			c--
+		}
+		if closer.count == 0 {
+			p.removeNode(closer.node)
+			p.delim = append(p.delim[:c], p.delim[c+1:]...)
+		}
+	}
+	if stackBottom < 0 {
+		p.delim = nil
+	} else {
+		p.delim = p.delim[:stackBottom+1]
+	}
+}
+
+func (p *inlineParser) removeNode(n *Node) {
+	if i := indexOf(p.nodes, n); i >= 0 {
+		p.nodes = append(p.nodes[:i], p.nodes[i+1:]...)
+	}
+}
+
+func indexOf(nodes []*Node, n *Node) int {
+	for i, x := range nodes {
+		if x == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// mergeText coalesces adjacent KindText nodes and drops empty ones.
+func mergeText(nodes []*Node) []*Node {
+	out := nodes[:0]
+	for _, n := range nodes {
+		if n.Kind == KindText {
+			if n.Literal == "" {
+				continue
+			}
+			if k := len(out) - 1; (k >= 0) && (out[k].Kind == KindText) {
+				out[k] = &Node{
+					Kind:    KindText,
+					Line:    out[k].Line,
+					Literal: out[k].Literal + n.Literal,
+				}
+				continue
+			}
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// entityEnd returns the length of the HTML entity or numeric character
+// reference at the start of s, or 0 if there isn't one.
+func entityEnd(s string) int {
+	i := 1
+	if (i < len(s)) && (s[i] == '#') {
+		i++
+		if (i < len(s)) && ((s[i] == 'x') || (s[i] == 'X')) {
+			i++
+		}
+	}
+	j := i
+	for (j < len(s)) && (j-i < 32) && isASCIIAlnum(s[j]) {
+		j++
+	}
+	if (j == i) || (j >= len(s)) || (s[j] != ';') {
+		return 0
+	}
+	if html.UnescapeString(s[:j+1]) == s[:j+1] {
+		return 0
+	}
+	return j + 1
+}
+
+func unescapeBackslashes(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	sb := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if (s[i] == '\\') && (i+1 < len(s)) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+func isScheme(s string) bool {
+	if (len(s) > 32) || !isASCIILetter(s[0]) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !isASCIIAlnum(c) && (c != '+') && (c != '.') && (c != '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func skipSpace(s string, i int) int {
+	for (i < len(s)) && ((s[i] == ' ') || (s[i] == '\t') || (s[i] == '\n')) {
+		i++
+	}
+	return i
+}
+
+func isASCIIPunct(c byte) bool {
+	return (('!' <= c) && (c <= '/')) || ((':' <= c) && (c <= '@')) ||
+		(('[' <= c) && (c <= '`')) || (('{' <= c) && (c <= '~'))
+}
+
+func isSpace(r rune) bool {
+	return unicode.IsSpace(r)
+}
+
+func isPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
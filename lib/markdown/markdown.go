@@ -0,0 +1,148 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package markdown parses Markdown text and renders it as HTML.
+//
+// It implements the subset of CommonMark and GitHub Flavored Markdown that the
+// blog posts actually use: ATX and setext headings, paragraphs, fenced and
+// indented code blocks, block quotes, ordered and unordered lists, tables,
+// thematic breaks and HTML blocks, plus code spans, emphasis, links, images
+// and autolinks. It is not a complete or fully conformant implementation.
+package markdown
+
+import (
+	"strings"
+)
+
+// Kind is a Node's type.
+type Kind uint8
+
+const (
+	KindDocument Kind = iota
+
+	// Block kinds.
+	KindBlockQuote
+	KindCodeBlock
+	KindHTMLBlock
+	KindHeading
+	KindList
+	KindListItem
+	KindParagraph
+	KindTable
+	KindTableCell
+	KindTableRow
+	KindThematicBreak
+
+	// Inline kinds.
+	KindCodeSpan
+	KindEmphasis
+	KindImage
+	KindLineBreak
+	KindLink
+	KindSoftBreak
+	KindStrong
+	KindText
+)
+
+// Alignment is a table cell's horizontal alignment.
+type Alignment uint8
+
+const (
+	AlignNone Alignment = iota
+	AlignLeft
+	AlignCenter
+	AlignRight
+)
+
+// Node is an element of the Markdown syntax tree.
+//
+// Which fields are meaningful depends on the Kind. Fields that do not apply
+// are left as their zero value.
+type Node struct {
+	Kind     Kind
+	Children []*Node
+
+	// Line is the 1-based line number, in the source text, of the start of
+	// the node.
+	Line int
+
+	// Level is the heading level, from 1 to 6, for a KindHeading.
+	Level int
+
+	// Ordered, Start and Tight apply to a KindList. Start is the first item's
+	// number, for an ordered list. A tight list's paragraphs are rendered
+	// without <p> tags.
+	Ordered bool
+	Start   int
+	Tight   bool
+
+	// Info is the info string (the text after the opening fence) of a fenced
+	// KindCodeBlock. Its first word is conventionally the language name.
+	Info string
+
+	// Literal is the text content of a KindCodeBlock, KindCodeSpan,
+	// KindHTMLBlock or KindText.
+	Literal string
+
+	// Destination and Title apply to a KindLink or KindImage.
+	Destination string
+	Title       string
+
+	// Align and Header apply to a KindTableCell.
+	Align  Alignment
+	Header bool
+}
+
+// Walk calls f for n and then, if f returned true, for each of n's
+// descendants in depth-first order.
+func (n *Node) Walk(f func(*Node) bool) {
+	if !f(n) {
+		return
+	}
+	for _, c := range n.Children {
+		c.Walk(f)
+	}
+}
+
+// IsBlock returns whether n is a block (as opposed to inline) element.
+func (n *Node) IsBlock() bool {
+	return n.Kind < KindCodeSpan
+}
+
+// PlainText returns the text content of n, without any markup. Soft and hard
+// line breaks become spaces. Images contribute their alt text.
+func PlainText(n *Node) string {
+	sb := &strings.Builder{}
+	plainText(sb, n)
+	return sb.String()
+}
+
+func plainText(sb *strings.Builder, n *Node) {
+	switch n.Kind {
+	case KindCodeBlock, KindCodeSpan, KindText:
+		sb.WriteString(n.Literal)
+		return
+	case KindHTMLBlock, KindThematicBreak:
+		return
+	case KindLineBreak, KindSoftBreak:
+		sb.WriteByte(' ')
+		return
+	}
+	for i, c := range n.Children {
+		if (i > 0) && c.IsBlock() {
+			sb.WriteByte('\n')
+		}
+		plainText(sb, c)
+	}
+}
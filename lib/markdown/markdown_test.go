@@ -0,0 +1,156 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package markdown
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func render(src string) string {
+	buf := &bytes.Buffer{}
+	(&Renderer{}).Render(buf, Parse([]byte(src)))
+	return buf.String()
+}
+
+func TestRender(t *testing.T) {
+	testCases := []struct {
+		src  string
+		want string
+	}{{
+		"# Title\n\nSome *emphasis* and **strong** text.",
+		"<h1>Title</h1>\n<p>Some <em>emphasis</em> and <strong>strong</strong> text.</p>\n",
+	}, {
+		"Setext\n======",
+		"<h1>Setext</h1>\n",
+	}, {
+		"A [link](https://example.com/ \"Title\") and ![an image](./a.png).",
+		"<p>A <a href=\"https://example.com/\" title=\"Title\">link</a> and <img src=\"./a.png\" alt=\"an image\">.</p>\n",
+	}, {
+		"[x](a\\)b) and [y](<a b>)",
+		"<p><a href=\"a)b\">x</a> and <a href=\"a b\">y</a></p>\n",
+	}, {
+		"A <https://example.com> autolink and a bare https://example.com/x URL.",
+		"<p>A <a href=\"https://example.com\">https://example.com</a> autolink and a bare <a href=\"https://example.com/x\">https://example.com/x</a> URL.</p>\n",
+	}, {
+		"Escaped \\*stars\\* and `code <span>`.",
+		"<p>Escaped *stars* and <code>code &lt;span&gt;</code>.</p>\n",
+	}, {
+		"Fish & chips < \"quotes\" > don't",
+		"<p>Fish &amp; chips &lt; &quot;quotes&quot; &gt; don't</p>\n",
+	}, {
+		"Line one  \nline two",
+		"<p>Line one<br>\nline two</p>\n",
+	}, {
+		"- one\n- two\n\n1. first\n2. second",
+		"<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n",
+	}, {
+		"> quoted\n> text",
+		"<blockquote>\n<p>quoted\ntext</p>\n</blockquote>\n",
+	}, {
+		"```go\nfunc f() {}\n```",
+		"<pre><code class=\"language-go\">func f() {}\n</code></pre>\n",
+	}, {
+		"    indented code",
+		"<pre><code>indented code\n</code></pre>\n",
+	}, {
+		"| a | b |\n|:--|--:|\n| 1 | 2 |",
+		"<table>\n<thead>\n<tr>\n<th align=\"left\">a</th>\n<th align=\"right\">b</th>\n</tr>\n</thead>\n" +
+			"<tbody>\n<tr>\n<td align=\"left\">1</td>\n<td align=\"right\">2</td>\n</tr>\n</tbody>\n</table>\n",
+	}, {
+		"---",
+		"<hr>\n",
+	}, {
+		"<div>\nraw\n</div>",
+		"<div>\nraw\n</div>\n",
+	}, {
+		// A backslash at the very end of an unfinished link destination.
+		"[](\\",
+		"<p>[](\\</p>\n",
+	}, {
+		"[x](\\",
+		"<p>[x](\\</p>\n",
+	}}
+
+	for _, tc := range testCases {
+		if got := render(tc.src); got != tc.want {
+			t.Errorf("src %q:\ngot  %q\nwant %q", tc.src, got, tc.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	doc := Parse([]byte("# Title\n\nText with a [link](./a.md).\n\n- item\n\n---"))
+	if doc.Kind != KindDocument {
+		t.Fatalf("Kind: got %d, want KindDocument", doc.Kind)
+	}
+
+	testCases := []struct {
+		kind Kind
+		line int
+	}{
+		{KindHeading, 1},
+		{KindParagraph, 3},
+		{KindList, 5},
+		{KindThematicBreak, 7},
+	}
+	if len(doc.Children) != len(testCases) {
+		t.Fatalf("len(Children): got %d, want %d", len(doc.Children), len(testCases))
+	}
+	for i, tc := range testCases {
+		if c := doc.Children[i]; (c.Kind != tc.kind) || (c.Line != tc.line) {
+			t.Errorf("Children[%d]: got kind %d line %d, want kind %d line %d",
+				i, c.Kind, c.Line, tc.kind, tc.line)
+		}
+	}
+
+	if h := doc.Children[0]; h.Level != 1 {
+		t.Errorf("heading Level: got %d, want 1", h.Level)
+	}
+	links := []*Node(nil)
+	doc.Walk(func(n *Node) bool {
+		if n.Kind == KindLink {
+			links = append(links, n)
+		}
+		return true
+	})
+	if (len(links) != 1) || (links[0].Destination != "./a.md") || (PlainText(links[0]) != "link") {
+		t.Errorf("links: got %d, want one to \"./a.md\"", len(links))
+	}
+}
+
+// TestNoPanic parses and renders random strings made mostly of the bytes that
+// are special to Markdown, checking that doing so never panics.
+func TestNoPanic(t *testing.T) {
+	const alphabet = "#*_`[]()<>!\\\"'&|:-=+.~ \t\nax1"
+	rng := rand.New(rand.NewSource(1))
+	buf := make([]byte, 0, 32)
+	for i := 0; i < 100000; i++ {
+		buf = buf[:0]
+		for n := rng.Intn(cap(buf)); n > 0; n-- {
+			buf = append(buf, alphabet[rng.Intn(len(alphabet))])
+		}
+		src := string(buf)
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("src %q: panic: %v", src, r)
+				}
+			}()
+			render(src)
+		}()
+	}
+}
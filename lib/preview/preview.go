@@ -0,0 +1,244 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package preview serves a generated web site on a local HTTP server. It
+// rebuilds the site whenever its source files change and tells any open
+// browser tabs to reload.
+//
+// Generated files are served from memory, so previewing doesn't modify the
+// working tree. Everything else, such as images, is served from disk, except
+// for hidden files and directories (those whose names start with a dot, such
+// as .git), which GitHub Pages doesn't publish either.
+package preview
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BuildFunc generates the web site, returning the contents of each generated
+// file keyed by its slash-separated path, such as "blog/2021/foo.html".
+type BuildFunc func() (map[string][]byte, error)
+
+// pollInterval is how often the watched files are checked for changes. Polling
+// is less efficient than OS-specific file change notification, but it is
+// portable and doesn't need any third party packages.
+const pollInterval = 250 * time.Millisecond
+
+// reloadPath is the URL path of the Server-Sent Events stream that tells each
+// page to reload.
+const reloadPath = "/_preview/reload"
+
+// reloadScript is inserted into every generated HTML page.
+const reloadScript = `<script>new EventSource("` + reloadPath + `").onmessage = function() { location.reload(); };</script>`
+
+type server struct {
+	build      BuildFunc
+	watch      []string
+	fileServer http.Handler
+
+	mu      sync.Mutex
+	files   map[string][]byte
+	clients map[chan struct{}]bool
+}
+
+// ListenAndServe builds the site and serves it on addr, such as
+// "localhost:8000". It watches the named files and directories (which are
+// walked recursively) and rebuilds the site when any of them change.
+//
+// A failed rebuild is logged and the previous build continues to be served.
+func ListenAndServe(addr string, watch []string, build BuildFunc) error {
+	s := &server{
+		build:      build,
+		watch:      watch,
+		fileServer: http.FileServer(http.Dir(".")),
+		clients:    map[chan struct{}]bool{},
+	}
+	files, err := build()
+	if err != nil {
+		return err
+	}
+	s.files = files
+	go s.poll()
+
+	log.Printf("preview: serving on http://%s/", addr)
+	return http.ListenAndServe(addr, s)
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == reloadPath {
+		s.serveReload(w, r)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+	if (name == "") || strings.HasSuffix(r.URL.Path, "/") {
+		name = path.Join(name, "index.html")
+	}
+
+	s.mu.Lock()
+	contents, ok := s.files[name]
+	_, isDir := s.files[path.Join(name, "index.html")]
+	s.mu.Unlock()
+
+	if !ok {
+		if isDir {
+			http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+			return
+		} else if isHidden(name) {
+			http.NotFound(w, r)
+			return
+		}
+		s.fileServer.ServeHTTP(w, r)
+		return
+	}
+	if path.Ext(name) == ".html" {
+		contents = injectReloadScript(contents)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(contents))
+}
+
+// isHidden returns whether any element of the slash-separated path name, such
+// as ".git/config", starts with a dot.
+func isHidden(name string) bool {
+	for _, e := range strings.Split(name, "/") {
+		if strings.HasPrefix(e, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// serveReload streams an event to the client whenever the site is rebuilt
+// (with different output).
+func (s *server) serveReload(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.clients[ch] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-ch:
+			fmt.Fprint(w, "data: reload\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// poll rebuilds the site whenever the watched files' sizes or modification
+// times change.
+func (s *server) poll() {
+	stamp := s.stamp()
+	for range time.Tick(pollInterval) {
+		if newStamp := s.stamp(); newStamp != stamp {
+			stamp = newStamp
+			s.rebuild()
+		}
+	}
+}
+
+func (s *server) rebuild() {
+	start := time.Now()
+	files, err := s.build()
+	if err != nil {
+		log.Printf("preview: rebuild failed: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := []string(nil)
+	for name, contents := range files {
+		if old, ok := s.files[name]; !ok || !bytes.Equal(old, contents) {
+			changed = append(changed, name)
+		}
+	}
+	s.files = files
+	if len(changed) == 0 {
+		log.Printf("preview: rebuilt in %v, no changes", time.Since(start).Round(time.Millisecond))
+		return
+	}
+	sort.Strings(changed)
+	log.Printf("preview: rebuilt in %v, changed %s", time.Since(start).Round(time.Millisecond),
+		strings.Join(changed, ", "))
+	for ch := range s.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// stamp summarizes the names, sizes and modification times of the watched
+// files. Any change to the watched files changes the stamp.
+func (s *server) stamp() string {
+	sb := &strings.Builder{}
+	for _, w := range s.watch {
+		filepath.Walk(w, func(filename string, info os.FileInfo, err error) error {
+			if err != nil {
+				// The file may have been removed since its directory was
+				// read. Just note the error in the stamp.
+				fmt.Fprintf(sb, "%s: %v\n", filename, err)
+				return nil
+			}
+			if !info.IsDir() {
+				fmt.Fprintf(sb, "%s %d %d\n", filename, info.Size(), info.ModTime().UnixNano())
+			}
+			return nil
+		})
+	}
+	return sb.String()
+}
+
+// injectReloadScript inserts the reloadScript before the closing body tag
+// (or at the end, if there isn't one).
+func injectReloadScript(contents []byte) []byte {
+	i := bytes.LastIndex(contents, []byte("</body>"))
+	if i < 0 {
+		i = len(contents)
+	}
+	dst := make([]byte, 0, len(contents)+len(reloadScript)+1)
+	dst = append(dst, contents[:i]...)
+	dst = append(dst, reloadScript...)
+	dst = append(dst, '\n')
+	dst = append(dst, contents[i:]...)
+	return dst
+}
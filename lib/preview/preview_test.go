@@ -0,0 +1,67 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preview
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServeHTTP(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{".git/config", ".gitignore", "blog/a.png", "blog/.b.png"} {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(name), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &server{
+		fileServer: http.FileServer(http.Dir(dir)),
+		files: map[string][]byte{
+			"index.html":           []byte("<p>Home</p>"),
+			"blog/2021/index.html": []byte("<p>2021</p>"),
+		},
+	}
+
+	testCases := []struct {
+		path string
+		want int
+	}{
+		{"/", http.StatusOK},
+		{"/blog/2021", http.StatusMovedPermanently},
+		{"/blog/2021/", http.StatusOK},
+		{"/blog/a.png", http.StatusOK},
+		{"/blog/.b.png", http.StatusNotFound},
+		{"/.git/config", http.StatusNotFound},
+		{"/.git/", http.StatusNotFound},
+		{"/.gitignore", http.StatusNotFound},
+		{"/blog/../.git/config", http.StatusNotFound},
+		{"/nonexistent", http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+		if got := w.Code; got != tc.want {
+			t.Errorf("%s: got status %d, want %d", tc.path, got, tc.want)
+		}
+	}
+}
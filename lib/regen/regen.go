@@ -0,0 +1,337 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package regen describes and runs the generators: the "//go:build ignore"
+// programs, alongside the blog posts, that draw those posts' images.
+//
+// A manifest (a JSON file) lists each generator's program, the other files it
+// reads, the files it writes and any follow-up commands (such as assembling
+// PNG frames into an animated GIF) that used to be run by hand. Programs that
+// only print to stdout or verify an algorithm, such as eisel-lemire.go, aren't
+// generators.
+//
+// A generator can list Go modules that its program imports but that go.mod
+// doesn't require, such as github.com/google/wuffs for dumbindent-animation.go.
+// Like one with missing tools, it is skipped (with a reason) until go.mod
+// requires them, instead of failing to build.
+//
+// Running a generator builds its program (from within the repository, so that
+// the go.mod file applies) and then runs it in a working directory. That's
+// usually the program's own directory but it can be a scratch directory that
+// holds copies of the inputs.
+package regen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Manifest lists the generators.
+type Manifest struct {
+	Generators []*Generator `json:"generators"`
+}
+
+// Generator is a program that writes some of the repository's files.
+//
+// Apart from Program, which is relative to the repository root, file names
+// are relative to the program's directory.
+type Generator struct {
+	// Program is the Go source file, such as "blog/2022/qoir.go".
+	Program string `json:"program"`
+
+	// Inputs are the files that the program (or its Steps) reads, other than
+	// the program itself.
+	Inputs []string `json:"inputs,omitempty"`
+
+	// Outputs are the (committed) files that the program and its Steps write.
+	Outputs []string `json:"outputs"`
+
+	// Tools are the external programs, such as "convert", that the program
+	// or its Steps need.
+	Tools []string `json:"tools,omitempty"`
+
+	// Modules are the Go modules, such as "github.com/google/wuffs", that the
+	// program imports but that go.mod might not require.
+	Modules []string `json:"modules,omitempty"`
+
+	// Steps are commands to run, in order, after the program. Arguments
+	// containing '*', '?' or '[' are expanded, like a shell glob, to the
+	// matching files (sorted by name) in the working directory.
+	Steps [][]string `json:"steps,omitempty"`
+
+	// Temporaries are files (or globs) that are left over after running the
+	// program and its Steps. They are deleted afterwards.
+	Temporaries []string `json:"temporaries,omitempty"`
+}
+
+// Dir returns the program's directory, relative to the repository root.
+func (g *Generator) Dir() string {
+	return path.Dir(g.Program)
+}
+
+// LoadManifest loads and checks the named manifest file.
+func LoadManifest(filename string) (*Manifest, error) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.DisallowUnknownFields()
+	m := &Manifest{}
+	if err := dec.Decode(m); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	programs := map[string]bool{}
+	outputs := map[string]string{}
+	for _, g := range m.Generators {
+		if !isRelative(g.Program) || (path.Ext(g.Program) != ".go") {
+			return nil, fmt.Errorf("%s: invalid program %q", filename, g.Program)
+		} else if programs[g.Program] {
+			return nil, fmt.Errorf("%s: duplicate program %q", filename, g.Program)
+		} else if len(g.Outputs) == 0 {
+			return nil, fmt.Errorf("%s: %s: no outputs", filename, g.Program)
+		}
+		programs[g.Program] = true
+
+		for _, names := range [][]string{g.Inputs, g.Outputs, g.Temporaries} {
+			for _, name := range names {
+				if !isRelative(name) {
+					return nil, fmt.Errorf("%s: %s: invalid file name %q", filename, g.Program, name)
+				}
+			}
+		}
+		for _, o := range g.Outputs {
+			o = path.Join(g.Dir(), o)
+			if other := outputs[o]; other != "" {
+				return nil, fmt.Errorf("%s: %s is an output of both %s and %s", filename, o, other, g.Program)
+			}
+			outputs[o] = g.Program
+		}
+		for _, step := range g.Steps {
+			if len(step) == 0 {
+				return nil, fmt.Errorf("%s: %s: empty step", filename, g.Program)
+			}
+		}
+	}
+	return m, nil
+}
+
+// isRelative returns whether name is a clean, slash-separated, relative path
+// that stays within its directory.
+func isRelative(name string) bool {
+	return (name != "") && (name == path.Clean(name)) && !path.IsAbs(name) &&
+		(name != "..") && !strings.HasPrefix(name, "../") && !strings.Contains(name, `\`)
+}
+
+// moduleFiles are, relative to the repository root, files that every
+// generator implicitly depends on. Upgrading a dependency (such as the fonts
+// in golang.org/x/image) can change what the programs draw.
+var moduleFiles = []string{"go.mod", "go.sum"}
+
+// Hash returns a hash of everything that the generator's outputs depend on:
+// its manifest entry, its program, its inputs and the module files. root is
+// the repository root.
+func (g *Generator) Hash(root string) (string, error) {
+	h := sha256.New()
+	entry, err := json.Marshal(g)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "manifest %d\n%s\n", len(entry), entry)
+
+	names := append([]string(nil), moduleFiles...)
+	names = append(names, g.Program)
+	for _, i := range g.Inputs {
+		names = append(names, path.Join(g.Dir(), i))
+	}
+	for _, name := range names {
+		contents, err := ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "file %s %d\n", name, len(contents))
+		h.Write(contents)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// MissingTools returns those of the generator's Tools that aren't installed.
+func (g *Generator) MissingTools() (missing []string) {
+	for _, t := range g.Tools {
+		if _, err := exec.LookPath(t); err != nil {
+			missing = append(missing, t)
+		}
+	}
+	return missing
+}
+
+// MissingModules returns those of the generator's Modules that the go.mod
+// file in root doesn't require.
+func (g *Generator) MissingModules(root string) (missing []string) {
+	for _, m := range g.Modules {
+		list := exec.Command("go", "list", "-m", m)
+		list.Dir = root
+		if err := list.Run(); err != nil {
+			missing = append(missing, m)
+		}
+	}
+	return missing
+}
+
+// Run builds the generator's program and runs it, and then its Steps, in
+// workDir. root is the repository root. The commands' output is written to
+// stdout and stderr. Temporaries are deleted afterwards, even if a command
+// fails.
+func (g *Generator) Run(root string, workDir string, stdout io.Writer, stderr io.Writer) (retErr error) {
+	if missing := g.MissingTools(); len(missing) > 0 {
+		return fmt.Errorf("%s: missing tools: %s", g.Program, strings.Join(missing, ", "))
+	}
+	if missing := g.MissingModules(root); len(missing) > 0 {
+		return fmt.Errorf("%s: go.mod doesn't require: %s", g.Program, strings.Join(missing, ", "))
+	}
+
+	binDir, err := ioutil.TempDir("", "regen")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(binDir)
+	bin := filepath.Join(binDir, strings.TrimSuffix(path.Base(g.Program), ".go"))
+
+	build := exec.Command("go", "build", "-o", bin, path.Base(g.Program))
+	build.Dir = filepath.Join(root, filepath.FromSlash(g.Dir()))
+	build.Stdout, build.Stderr = stdout, stderr
+	if err := build.Run(); err != nil {
+		return fmt.Errorf("%s: go build: %v", g.Program, err)
+	}
+
+	defer func() {
+		if err := g.removeTemporaries(workDir); (err != nil) && (retErr == nil) {
+			retErr = err
+		}
+	}()
+
+	cmd := exec.Command(bin)
+	cmd.Dir = workDir
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v", g.Program, err)
+	}
+
+	for _, step := range g.Steps {
+		args := []string(nil)
+		for _, arg := range step[1:] {
+			if !strings.ContainsAny(arg, "*?[") {
+				args = append(args, arg)
+				continue
+			}
+			matches, err := glob(workDir, arg)
+			if err != nil {
+				return fmt.Errorf("%s: %v", g.Program, err)
+			} else if len(matches) == 0 {
+				return fmt.Errorf("%s: %s: no files match %q", g.Program, step[0], arg)
+			}
+			args = append(args, matches...)
+		}
+		cmd := exec.Command(step[0], args...)
+		cmd.Dir = workDir
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %s: %v", g.Program, step[0], err)
+		}
+	}
+	return nil
+}
+
+func (g *Generator) removeTemporaries(workDir string) error {
+	for _, t := range g.Temporaries {
+		matches, err := glob(workDir, t)
+		if err != nil {
+			return fmt.Errorf("%s: %v", g.Program, err)
+		}
+		for _, m := range matches {
+			if err := os.RemoveAll(filepath.Join(workDir, filepath.FromSlash(m))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// glob returns the slash-separated names, relative to dir, of the files that
+// match the pattern.
+func glob(dir string, pattern string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range matches {
+		rel, err := filepath.Rel(dir, m)
+		if err != nil {
+			return nil, err
+		}
+		matches[i] = filepath.ToSlash(rel)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// ReadSums reads a file written by WriteSums, returning each program's Hash.
+// A missing file is not an error: it holds no sums.
+func ReadSums(filename string) (map[string]string, error) {
+	sums := map[string]string{}
+	src, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return sums, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for i, line := range strings.Split(string(src), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: invalid line", filename, i+1)
+		}
+		sums[fields[0]] = fields[1]
+	}
+	return sums, nil
+}
+
+// WriteSums writes each program's Hash to the named file, one per line and
+// sorted by program.
+func WriteSums(filename string, sums map[string]string) error {
+	programs := make([]string, 0, len(sums))
+	for p := range sums {
+		programs = append(programs, p)
+	}
+	sort.Strings(programs)
+	buf := &bytes.Buffer{}
+	for _, p := range programs {
+		fmt.Fprintf(buf, "%s %s\n", p, sums[p])
+	}
+	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
+}
@@ -0,0 +1,215 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package regen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// CopyInputs copies the generator's inputs from the repository root to
+// workDir, so that the generator can be run in workDir.
+func (g *Generator) CopyInputs(root string, workDir string) error {
+	for _, i := range g.Inputs {
+		src, err := ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(path.Join(g.Dir(), i))))
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(workDir, filepath.FromSlash(i))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(dst, src, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Comparison is the result of comparing two encoded images pixel by pixel.
+type Comparison struct {
+	// Frames is the number of frames: 1 for a still image and possibly more
+	// for an animated GIF.
+	Frames int
+
+	// Pixels is the total number of pixels, over all frames.
+	Pixels int
+
+	// Differing is the number of pixels that differ by more than the
+	// threshold passed to Compare.
+	Differing int
+
+	// MaxDelta is the largest difference, over all pixels and color channels.
+	MaxDelta int
+
+	// Diff highlights the differences. It is a faded copy of the wanted image,
+	// with pixels that differ by more than the threshold in red and those that
+	// differ by less in orange. An animation's frames are stacked vertically.
+	Diff *image.NRGBA
+}
+
+// Fraction returns the fraction of pixels that differ by more than the
+// threshold.
+func (c *Comparison) Fraction() float64 {
+	if c.Pixels == 0 {
+		return 0
+	}
+	return float64(c.Differing) / float64(c.Pixels)
+}
+
+// Compare decodes and compares two PNG, GIF or JPEG images. Two pixels differ
+// by the largest difference in their (non-alpha-premultiplied, 8 bits per
+// channel) red, green, blue or alpha values. Fully transparent pixels don't
+// differ, regardless of their color.
+//
+// Animated GIFs are compared frame by frame, after compositing each frame
+// onto the previous ones, so that differently optimized GIFs of the same
+// animation compare equal.
+//
+// It returns an error if either image can't be decoded or if their sizes or
+// numbers of frames don't match.
+func Compare(want []byte, got []byte, threshold int) (*Comparison, error) {
+	wantFrames, err := decodeFrames(want)
+	if err != nil {
+		return nil, fmt.Errorf("decoding committed image: %v", err)
+	}
+	gotFrames, err := decodeFrames(got)
+	if err != nil {
+		return nil, fmt.Errorf("decoding generated image: %v", err)
+	}
+	if len(wantFrames) != len(gotFrames) {
+		return nil, fmt.Errorf("committed image has %d frame(s), generated image has %d",
+			len(wantFrames), len(gotFrames))
+	}
+	wb, gb := wantFrames[0].Bounds(), gotFrames[0].Bounds()
+	if wb.Size() != gb.Size() {
+		return nil, fmt.Errorf("committed image is %dx%d, generated image is %dx%d",
+			wb.Dx(), wb.Dy(), gb.Dx(), gb.Dy())
+	}
+
+	w, h := wb.Dx(), wb.Dy()
+	c := &Comparison{
+		Frames: len(wantFrames),
+		Pixels: len(wantFrames) * w * h,
+		Diff:   image.NewNRGBA(image.Rect(0, 0, w, len(wantFrames)*h)),
+	}
+	for f := range wantFrames {
+		wf, gf := wantFrames[f], gotFrames[f]
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				wc := wf.NRGBAAt(wf.Rect.Min.X+x, wf.Rect.Min.Y+y)
+				gc := gf.NRGBAAt(gf.Rect.Min.X+x, gf.Rect.Min.Y+y)
+				d := delta(wc, gc)
+				if c.MaxDelta < d {
+					c.MaxDelta = d
+				}
+
+				out := color.NRGBA{}
+				switch {
+				case d > threshold:
+					c.Differing++
+					out = color.NRGBA{0xFF, 0x00, 0x00, 0xFF}
+				case d > 0:
+					out = color.NRGBA{0xFF, 0xA5, 0x00, 0xFF}
+				default:
+					// Fade the wanted pixel, composited onto white, so that
+					// the differences stand out.
+					luma := (299*int(wc.R) + 587*int(wc.G) + 114*int(wc.B)) / 1000
+					luma = 255 - ((255-luma)*int(wc.A)/255)/4
+					out = color.NRGBA{uint8(luma), uint8(luma), uint8(luma), 0xFF}
+				}
+				c.Diff.SetNRGBA(x, (f*h)+y, out)
+			}
+		}
+	}
+	return c, nil
+}
+
+func delta(a color.NRGBA, b color.NRGBA) int {
+	if (a.A == 0) && (b.A == 0) {
+		return 0
+	}
+	d := 0
+	for _, p := range [4][2]uint8{{a.R, b.R}, {a.G, b.G}, {a.B, b.B}, {a.A, b.A}} {
+		e := int(p[0]) - int(p[1])
+		if e < 0 {
+			e = -e
+		}
+		if d < e {
+			d = e
+		}
+	}
+	return d
+}
+
+// decodeFrames decodes an image, returning its frames as seen by a viewer.
+func decodeFrames(src []byte) ([]*image.NRGBA, error) {
+	if !bytes.HasPrefix(src, []byte("GIF8")) {
+		m, _, err := image.Decode(bytes.NewReader(src))
+		if err != nil {
+			return nil, err
+		}
+		return []*image.NRGBA{toNRGBA(m)}, nil
+	}
+
+	g, err := gif.DecodeAll(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	} else if len(g.Image) == 0 {
+		return nil, errors.New("GIF has no frames")
+	}
+	canvas := image.NewNRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	frames := make([]*image.NRGBA, 0, len(g.Image))
+	for i, p := range g.Image {
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		previous := (*image.NRGBA)(nil)
+		if disposal == gif.DisposalPrevious {
+			previous = toNRGBA(canvas)
+		}
+
+		draw.Draw(canvas, p.Bounds(), p, p.Bounds().Min, draw.Over)
+		frames = append(frames, toNRGBA(canvas))
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, p.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return frames, nil
+}
+
+// toNRGBA returns a copy of m as an *image.NRGBA.
+func toNRGBA(m image.Image) *image.NRGBA {
+	dst := image.NewNRGBA(m.Bounds())
+	draw.Draw(dst, dst.Bounds(), m, m.Bounds().Min, draw.Src)
+	return dst
+}
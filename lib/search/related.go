@@ -0,0 +1,94 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"math"
+	"sort"
+)
+
+// Similarities returns how similar each pair of documents is, from 0 (no terms
+// in common) to 1 (the same terms, in the same proportions). The result is a
+// symmetric matrix: s[i][j] is the similarity of docs[i] and docs[j].
+//
+// Similarity is the cosine similarity of the documents' TF-IDF
+// (https://en.wikipedia.org/wiki/Tf%E2%80%93idf) vectors, using sub-linear
+// (logarithmic) term frequencies so that long documents that repeat a few
+// terms don't dominate. Documents are tokenized and stemmed by Tokenize.
+func Similarities(docs []string) [][]float64 {
+	// Count each document's terms and the number of documents that each term
+	// occurs in.
+	counts := make([]map[string]int, len(docs))
+	df := map[string]int{}
+	for i, doc := range docs {
+		counts[i] = map[string]int{}
+		for _, t := range Tokenize(doc) {
+			if counts[i][t] == 0 {
+				df[t]++
+			}
+			counts[i][t]++
+		}
+	}
+
+	// Weight each term and normalize each vector to unit length. A term that
+	// occurs in every document has zero weight. Floating point addition isn't
+	// associative, so terms are visited in sorted order (not Go's random map
+	// order) to get the same results every time.
+	vectors := make([]map[string]float64, len(docs))
+	sortedTerms := make([][]string, len(docs))
+	for i, c := range counts {
+		terms := make([]string, 0, len(c))
+		for t := range c {
+			terms = append(terms, t)
+		}
+		sort.Strings(terms)
+		sortedTerms[i] = terms
+
+		v := make(map[string]float64, len(c))
+		norm := 0.0
+		for _, t := range terms {
+			w := (1 + math.Log(float64(c[t]))) * math.Log(float64(len(docs))/float64(df[t]))
+			v[t] = w
+			norm += w * w
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for t := range v {
+				v[t] /= norm
+			}
+		}
+		vectors[i] = v
+	}
+
+	s := make([][]float64, len(docs))
+	for i := range s {
+		s[i] = make([]float64, len(docs))
+	}
+	for i := range vectors {
+		for j := i; j < len(vectors); j++ {
+			// Iterate over the smaller vector's terms.
+			a, b, terms := vectors[i], vectors[j], sortedTerms[i]
+			if len(a) > len(b) {
+				a, b, terms = b, a, sortedTerms[j]
+			}
+			dot := 0.0
+			for _, t := range terms {
+				dot += a[t] * b[t]
+			}
+			s[i][j], s[j][i] = dot, dot
+		}
+	}
+	return s
+}
@@ -0,0 +1,252 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package search implements a small full-text search index over the sections
+// (the text under each heading) of the blog posts.
+//
+// The index is an inverted index from stemmed terms to sections. It is built
+// once, serialized as JSON and then queried offline. Results are ranked by
+// BM25 (https://en.wikipedia.org/wiki/Okapi_BM25), favoring sections that
+// match more of the query's terms.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Index is a search index. Its JSON form is intentionally terse, since the
+// index covers the full text of every post.
+type Index struct {
+	// Posts are the indexed blog posts.
+	Posts []Post `json:"posts"`
+
+	// Sections are the indexed sections. Each one belongs to a Post.
+	Sections []Section `json:"sections"`
+
+	// Terms maps each stemmed term to its postings: pairs of (section index
+	// delta, term frequency). The section index delta is the difference
+	// between this posting's section index and the previous posting's (or
+	// zero, for the first posting). Delta encoding makes the JSON smaller.
+	Terms map[string][]int `json:"terms"`
+}
+
+// Post is an indexed blog post.
+type Post struct {
+	Title string `json:"t"`
+	URL   string `json:"u"`
+}
+
+// Section is part of a blog post: the text under one of its headings. The
+// first section of each post is the text before any heading.
+type Section struct {
+	// Post indexes the Index's Posts.
+	Post int `json:"p"`
+
+	// Anchor is the heading's ID. It is empty for a post's first section.
+	Anchor string `json:"a,omitempty"`
+
+	// Heading is the heading's plain text. It is empty for a post's first
+	// section.
+	Heading string `json:"h,omitempty"`
+
+	// Length is the number of terms in the section.
+	Length int `json:"n"`
+}
+
+// Hit is a search result.
+type Hit struct {
+	Post    Post
+	Section Section
+	Score   float64
+}
+
+// URL returns the hit's post URL, plus a fragment for the hit's section.
+func (h *Hit) URL() string {
+	if h.Section.Anchor == "" {
+		return h.Post.URL
+	}
+	return h.Post.URL + "#" + h.Section.Anchor
+}
+
+// Builder builds an Index.
+type Builder struct {
+	index    Index
+	postings map[string][]int
+}
+
+// NewBuilder returns a new Builder.
+func NewBuilder() *Builder {
+	return &Builder{
+		postings: map[string][]int{},
+	}
+}
+
+// AddPost adds a post, returning the index to pass to AddSection.
+func (b *Builder) AddPost(p Post) int {
+	b.index.Posts = append(b.index.Posts, p)
+	return len(b.index.Posts) - 1
+}
+
+// AddSection adds a section of the post'th post, containing the given text.
+// The heading itself is also indexed.
+func (b *Builder) AddSection(post int, anchor string, heading string, text string) {
+	terms := Tokenize(heading + "\n" + text)
+	sectionIndex := len(b.index.Sections)
+	b.index.Sections = append(b.index.Sections, Section{
+		Post:    post,
+		Anchor:  anchor,
+		Heading: heading,
+		Length:  len(terms),
+	})
+
+	counts := map[string]int{}
+	for _, t := range terms {
+		counts[t]++
+	}
+	for t, n := range counts {
+		b.postings[t] = append(b.postings[t], sectionIndex, n)
+	}
+}
+
+// Index returns the built Index.
+func (b *Builder) Index() *Index {
+	terms := make(map[string][]int, len(b.postings))
+	for t, p := range b.postings {
+		// Sort the postings by section index (they're probably already
+		// sorted) and then delta-encode them.
+		pairs := make([][2]int, 0, len(p)/2)
+		for i := 0; i < len(p); i += 2 {
+			pairs = append(pairs, [2]int{p[i], p[i+1]})
+		}
+		sort.Slice(pairs, func(i int, j int) bool {
+			return pairs[i][0] < pairs[j][0]
+		})
+		encoded := make([]int, 0, len(p))
+		prev := 0
+		for _, pair := range pairs {
+			encoded = append(encoded, pair[0]-prev, pair[1])
+			prev = pair[0]
+		}
+		terms[t] = encoded
+	}
+	idx := b.index
+	idx.Terms = terms
+	return &idx
+}
+
+// BM25 parameters.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Search returns the (at most) n best matching sections for the query, best
+// first. Query terms are tokenized and stemmed the same way as the indexed
+// text, so that searching for "repeat offsets" also finds "repeated offset".
+func (x *Index) Search(query string, n int) []Hit {
+	if len(x.Sections) == 0 {
+		return nil
+	}
+	totalLength := 0
+	for _, s := range x.Sections {
+		totalLength += s.Length
+	}
+	avgLength := float64(totalLength) / float64(len(x.Sections))
+	numSections := float64(len(x.Sections))
+
+	queryTerms := Tokenize(query)
+	seen := map[string]bool{}
+	scores := map[int]float64{}
+	matches := map[int]int{}
+	numQueryTerms := 0
+	for _, t := range queryTerms {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		numQueryTerms++
+
+		postings := x.Terms[t]
+		df := float64(len(postings) / 2)
+		idf := math.Log(1 + (numSections-df+0.5)/(df+0.5))
+		section := 0
+		for i := 0; i+1 < len(postings); i += 2 {
+			section += postings[i]
+			if (section < 0) || (section >= len(x.Sections)) {
+				break
+			}
+			tf := float64(postings[i+1])
+			norm := 1 - bm25B + bm25B*float64(x.Sections[section].Length)/avgLength
+			scores[section] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+			matches[section]++
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for section, score := range scores {
+		// Scale by the fraction of query terms matched, so that a section
+		// matching all of them outranks one that matches only the rarest.
+		score *= float64(matches[section]) / float64(numQueryTerms)
+		s := x.Sections[section]
+		hits = append(hits, Hit{
+			Post:    x.Posts[s.Post],
+			Section: s,
+			Score:   score,
+		})
+	}
+	sort.Slice(hits, func(i int, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].URL() < hits[j].URL()
+	})
+	if len(hits) > n {
+		hits = hits[:n]
+	}
+	return hits
+}
+
+// Tokenize splits text into lower case, stemmed terms, dropping common
+// English stop words and single characters. Underscores are part of a term, so
+// that identifiers like "co_await" are kept whole.
+func Tokenize(text string) []string {
+	terms := []string(nil)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+		word = strings.Trim(word, "_")
+		if (len(word) < 2) || stopWords[word] {
+			continue
+		}
+		terms = append(terms, Stem(word))
+	}
+	return terms
+}
+
+func isSeparator(r rune) bool {
+	return (r != '_') && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		an and are as at be but by can do does for from had has have how if in
+		into is it its of on or so than that the their then there these they
+		this to was we were what when which while who will with would you your
+	`) {
+		stopWords[w] = true
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+// Stem returns the stem of a lower case English word, using the Porter
+// stemming algorithm (https://tartarus.org/martin/PorterStemmer/), so that
+// "offsets", "offset" and "offsetting" all become "offset". Words that aren't
+// entirely made of ASCII lower case letters are returned unchanged.
+func Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if c := word[i]; (c < 'a') || ('z' < c) {
+			return word
+		}
+	}
+	s := &stemmer{b: []byte(word), k: len(word) - 1}
+	s.step1ab()
+	if s.k > 0 {
+		s.step1c()
+		s.step2()
+		s.step3()
+		s.step4()
+		s.step5()
+	}
+	return string(s.b[:s.k+1])
+}
+
+// stemmer holds the word being stemmed, b[:k+1]. j is a general offset into
+// b, set by ends.
+type stemmer struct {
+	b []byte
+	k int
+	j int
+}
+
+// cons returns whether b[i] is a consonant.
+func (s *stemmer) cons(i int) bool {
+	switch s.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return (i == 0) || !s.cons(i-1)
+	}
+	return true
+}
+
+// m measures the number of consonant sequences in b[:j+1]. With c a
+// consonant sequence and v a vowel sequence, and [x] meaning optional x:
+//
+//	[c][v]       gives 0
+//	[c]vc[v]     gives 1
+//	[c]vcvc[v]   gives 2
+func (s *stemmer) m() int {
+	n, i := 0, 0
+	for ; ; i++ {
+		if i > s.j {
+			return n
+		} else if !s.cons(i) {
+			break
+		}
+	}
+	for i++; ; i++ {
+		for ; ; i++ {
+			if i > s.j {
+				return n
+			} else if s.cons(i) {
+				break
+			}
+		}
+		n++
+		for i++; ; i++ {
+			if i > s.j {
+				return n
+			} else if !s.cons(i) {
+				break
+			}
+		}
+	}
+}
+
+// vowelInStem returns whether b[:j+1] contains a vowel.
+func (s *stemmer) vowelInStem() bool {
+	for i := 0; i <= s.j; i++ {
+		if !s.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleC returns whether b[i-1:i+1] is a double consonant.
+func (s *stemmer) doubleC(i int) bool {
+	return (i >= 1) && (s.b[i] == s.b[i-1]) && s.cons(i)
+}
+
+// cvc returns whether b[i-2:i+1] is consonant-vowel-consonant and the second
+// consonant is not 'w', 'x' or 'y'. This is used when trying to restore an
+// 'e' at the end of a short word, such as "cav(e)", "lov(e)" or "hop(e)" but
+// not "snow", "box" or "tray".
+func (s *stemmer) cvc(i int) bool {
+	if (i < 2) || !s.cons(i) || s.cons(i-1) || !s.cons(i-2) {
+		return false
+	}
+	switch s.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+// ends returns whether b[:k+1] ends with suffix, setting j to the end of the
+// stem before that suffix if so.
+func (s *stemmer) ends(suffix string) bool {
+	n := len(suffix)
+	if (n > s.k+1) || (string(s.b[s.k+1-n:s.k+1]) != suffix) {
+		return false
+	}
+	s.j = s.k - n
+	return true
+}
+
+// setTo replaces b[j+1:k+1] with r.
+func (s *stemmer) setTo(r string) {
+	s.b = append(s.b[:s.j+1], r...)
+	s.k = s.j + len(r)
+}
+
+// r replaces the suffix with r if the stem's measure is positive.
+func (s *stemmer) r(r string) {
+	if s.m() > 0 {
+		s.setTo(r)
+	}
+}
+
+// step1ab removes plurals and -ed or -ing, such as "caresses" to "caress",
+// "ponies" to "poni" and "meetings" to "meet".
+func (s *stemmer) step1ab() {
+	if s.b[s.k] == 's' {
+		if s.ends("sses") {
+			s.k -= 2
+		} else if s.ends("ies") {
+			s.setTo("i")
+		} else if s.b[s.k-1] != 's' {
+			s.k--
+		}
+	}
+	if s.ends("eed") {
+		if s.m() > 0 {
+			s.k--
+		}
+	} else if (s.ends("ed") || s.ends("ing")) && s.vowelInStem() {
+		s.k = s.j
+		if s.ends("at") {
+			s.setTo("ate")
+		} else if s.ends("bl") {
+			s.setTo("ble")
+		} else if s.ends("iz") {
+			s.setTo("ize")
+		} else if s.doubleC(s.k) {
+			s.k--
+			switch s.b[s.k] {
+			case 'l', 's', 'z':
+				s.k++
+			}
+		} else if s.j = s.k; (s.m() == 1) && s.cvc(s.k) {
+			s.setTo("e")
+		}
+	}
+}
+
+// step1c turns a terminal 'y' to an 'i' when there is another vowel in the
+// stem.
+func (s *stemmer) step1c() {
+	if s.ends("y") && s.vowelInStem() {
+		s.b[s.k] = 'i'
+	}
+}
+
+// step2 maps double suffixes to single ones, such as "-ization" (which is
+// "-ize" plus "-ation") to "-ize".
+func (s *stemmer) step2() {
+	for _, x := range step2Suffixes[s.b[s.k-1]] {
+		if s.ends(x[0]) {
+			s.r(x[1])
+			return
+		}
+	}
+}
+
+var step2Suffixes = map[byte][][2]string{
+	'a': {{"ational", "ate"}, {"tional", "tion"}},
+	'c': {{"enci", "ence"}, {"anci", "ance"}},
+	'e': {{"izer", "ize"}},
+	'g': {{"logi", "log"}},
+	'l': {{"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"}},
+	'o': {{"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"}},
+	's': {{"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"}, {"ousness", "ous"}},
+	't': {{"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"}},
+}
+
+// step3 deals with -ic-, -full, -ness etc.
+func (s *stemmer) step3() {
+	for _, x := range step3Suffixes[s.b[s.k]] {
+		if s.ends(x[0]) {
+			s.r(x[1])
+			return
+		}
+	}
+}
+
+var step3Suffixes = map[byte][][2]string{
+	'e': {{"icate", "ic"}, {"ative", ""}, {"alize", "al"}},
+	'i': {{"iciti", "ic"}},
+	'l': {{"ical", "ic"}, {"ful", ""}},
+	's': {{"ness", ""}},
+}
+
+// step4 removes -ant, -ence etc. in a context <c>vcvc<v>.
+func (s *stemmer) step4() {
+	found := false
+	for _, x := range step4Suffixes[s.b[s.k-1]] {
+		if s.ends(x) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		if !s.ends("ion") || (s.j < 0) || ((s.b[s.j] != 's') && (s.b[s.j] != 't')) {
+			return
+		}
+	}
+	if s.m() > 1 {
+		s.k = s.j
+	}
+}
+
+var step4Suffixes = map[byte][]string{
+	'a': {"al"},
+	'c': {"ance", "ence"},
+	'e': {"er"},
+	'i': {"ic"},
+	'l': {"able", "ible"},
+	'n': {"ant", "ement", "ment", "ent"},
+	'o': {"ou"},
+	's': {"ism"},
+	't': {"ate", "iti"},
+	'u': {"ous"},
+	'v': {"ive"},
+	'z': {"ize"},
+}
+
+// step5 removes a final -e if the measure is more than 1 and changes -ll to
+// -l if the measure is more than 1.
+func (s *stemmer) step5() {
+	s.j = s.k
+	if s.b[s.k] == 'e' {
+		if a := s.m(); (a > 1) || ((a == 1) && !s.cvc(s.k-1)) {
+			s.k--
+		}
+	}
+	if (s.b[s.k] == 'l') && s.doubleC(s.k) && (s.m() > 1) {
+		s.k--
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build ignore
+
+package main
+
+// regen.go re-runs the programs that generate the blog posts' images, as
+// listed in script/generators.json, each in its own directory. It should be
+// run from the repository root:
+//
+//	go run script/regen.go
+//
+// By default, it only runs generators whose inputs have changed since they
+// were last run, according to script/generators.sum (which it updates, and
+// which should be committed alongside the outputs). A generator that isn't in
+// that file has never been run. With the -all flag, it runs every generator.
+// Either way, it then reports which output files changed, so that they can be
+// reviewed and committed.
+//
+// With the -verify flag, it instead checks that the committed images are
+// reproducible. It runs every generator in a scratch directory and compares
+// what it draws with the committed files, pixel by pixel. An image passes if
+// at most a -tolerance fraction of its pixels differ by more than -threshold
+// (out of 255) in any color channel. For each image that differs at all, it
+// writes a diff image to the -diffdir directory.
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nigeltao/nigeltao.github.io/lib/regen"
+)
+
+var (
+	allFlag = flag.Bool("all", false,
+		"run every generator, not just those whose inputs have changed")
+	dryRunFlag = flag.Bool("n", false,
+		"list the generators that would run, without running them")
+	manifestFlag = flag.String("manifest", "script/generators.json", "the manifest file")
+	sumsFlag     = flag.String("sums", "script/generators.sum",
+		"the file recording the inputs' hashes as of each generator's last run")
+
+	verifyFlag = flag.Bool("verify", false,
+		"check that the committed images match what the generators draw, instead of updating them")
+	thresholdFlag = flag.Int("threshold", 0,
+		"for -verify, the largest per-channel difference (0-255) that two pixels can have and still match")
+	toleranceFlag = flag.Float64("tolerance", 0,
+		"for -verify, the largest fraction (0-1) of non-matching pixels that an image can have and still pass")
+	diffDirFlag = flag.String("diffdir", "",
+		"for -verify, the directory to write diff images to (default: a new temporary directory)")
+)
+
+func main() {
+	flag.Parse()
+	if err := main1(); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+
+func main1() error {
+	if _, err := os.Stat("go.mod"); err != nil {
+		return errors.New("regen.go should be run from the repository root")
+	}
+	m, err := regen.LoadManifest(*manifestFlag)
+	if err != nil {
+		return err
+	}
+	if *verifyFlag {
+		return verify(m)
+	}
+	sums, err := regen.ReadSums(*sumsFlag)
+	if err != nil {
+		return err
+	}
+
+	changed, failed, ran := []string(nil), []string(nil), 0
+	for _, g := range m.Generators {
+		hash, err := g.Hash(".")
+		if err != nil {
+			return err
+		}
+		if !*allFlag && (sums[g.Program] == hash) {
+			continue
+		}
+		if *dryRunFlag {
+			fmt.Println(g.Program)
+			continue
+		}
+
+		fmt.Printf("running %s\n", g.Program)
+		ran++
+		before := readOutputs(g)
+		if err := g.Run(".", filepath.FromSlash(g.Dir()), os.Stdout, os.Stderr); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			failed = append(failed, g.Program)
+			continue
+		}
+		after := readOutputs(g)
+
+		ok := true
+		for _, o := range g.Outputs {
+			name := path.Join(g.Dir(), o)
+			if after[o] == nil {
+				fmt.Fprintf(os.Stderr, "%s: did not write %s\n", g.Program, name)
+				ok = false
+			} else if before[o] == nil {
+				changed = append(changed, name+" (new)")
+			} else if !bytes.Equal(before[o], after[o]) {
+				changed = append(changed, name)
+			}
+		}
+		if !ok {
+			failed = append(failed, g.Program)
+			continue
+		}
+		sums[g.Program] = hash
+	}
+	if *dryRunFlag {
+		return nil
+	}
+
+	if ran > 0 {
+		if err := regen.WriteSums(*sumsFlag, sums); err != nil {
+			return err
+		}
+	}
+	if len(changed) == 0 {
+		fmt.Printf("ran %d generator(s): no output files changed\n", ran)
+	} else {
+		fmt.Printf("ran %d generator(s): %d output file(s) changed:\n", ran, len(changed))
+		for _, c := range changed {
+			fmt.Printf("\t%s\n", c)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d generator(s) failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// readOutputs returns the contents of the generator's output files, keyed by
+// their names in the manifest. Missing files have nil contents.
+func readOutputs(g *regen.Generator) map[string][]byte {
+	contents := map[string][]byte{}
+	for _, o := range g.Outputs {
+		if b, err := ioutil.ReadFile(filepath.FromSlash(path.Join(g.Dir(), o))); err == nil {
+			contents[o] = b
+		}
+	}
+	return contents
+}
+
+// verify runs each generator in a scratch directory and compares its outputs
+// with the committed files.
+func verify(m *regen.Manifest) error {
+	diffDir := *diffDirFlag
+	if diffDir == "" {
+		d, err := ioutil.TempDir("", "regen-diff")
+		if err != nil {
+			return err
+		}
+		diffDir = d
+	} else if err := os.MkdirAll(diffDir, 0755); err != nil {
+		return err
+	}
+
+	passed, failed, skipped, diffs := 0, 0, 0, 0
+	for _, g := range m.Generators {
+		if missing := g.MissingTools(); len(missing) > 0 {
+			fmt.Printf("SKIP %s: missing tools: %s\n", g.Program, strings.Join(missing, ", "))
+			skipped += len(g.Outputs)
+			continue
+		}
+		if missing := g.MissingModules("."); len(missing) > 0 {
+			fmt.Printf("SKIP %s: go.mod doesn't require: %s\n", g.Program, strings.Join(missing, ", "))
+			skipped += len(g.Outputs)
+			continue
+		}
+
+		workDir, err := ioutil.TempDir("", "regen")
+		if err != nil {
+			return err
+		}
+		err = g.CopyInputs(".", workDir)
+		if err == nil {
+			err = g.Run(".", workDir, ioutil.Discard, os.Stderr)
+		}
+		if err != nil {
+			os.RemoveAll(workDir)
+			fmt.Printf("FAIL %s: %v\n", g.Program, err)
+			failed += len(g.Outputs)
+			continue
+		}
+
+		for _, o := range g.Outputs {
+			name := path.Join(g.Dir(), o)
+			ok, diffName, err := verify1(name, filepath.Join(workDir, filepath.FromSlash(o)), diffDir)
+			if err != nil {
+				fmt.Printf("FAIL %s: %v\n", name, err)
+				failed++
+				continue
+			}
+			if diffName != "" {
+				diffs++
+			}
+			if ok {
+				passed++
+			} else {
+				failed++
+			}
+		}
+		os.RemoveAll(workDir)
+	}
+
+	fmt.Printf("%d passed, %d failed, %d skipped\n", passed, failed, skipped)
+	if diffs > 0 {
+		fmt.Printf("diff images are in %s\n", diffDir)
+	} else if *diffDirFlag == "" {
+		os.Remove(diffDir)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d image(s) are not reproducible", failed)
+	}
+	return nil
+}
+
+// verify1 compares the committed file name with its regenerated version,
+// printing the result. It returns whether the file passed and the name of the
+// diff image written, if any.
+func verify1(name string, regenerated string, diffDir string) (ok bool, diffName string, err error) {
+	want, err := ioutil.ReadFile(filepath.FromSlash(name))
+	if err != nil {
+		return false, "", err
+	}
+	got, err := ioutil.ReadFile(regenerated)
+	if err != nil {
+		return false, "", err
+	}
+	if bytes.Equal(want, got) {
+		fmt.Printf("PASS %s: identical\n", name)
+		return true, "", nil
+	}
+
+	c, err := regen.Compare(want, got, *thresholdFlag)
+	if err != nil {
+		return false, "", err
+	}
+	if c.MaxDelta > 0 {
+		diffName = filepath.Join(diffDir, strings.Replace(name, "/", "_", -1)+".diff.png")
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, c.Diff); err != nil {
+			return false, "", err
+		}
+		if err := ioutil.WriteFile(diffName, buf.Bytes(), 0644); err != nil {
+			return false, "", err
+		}
+	}
+
+	ok = c.Fraction() <= *toleranceFlag
+	result := "PASS"
+	if !ok {
+		result = "FAIL"
+	}
+	if c.MaxDelta == 0 {
+		fmt.Printf("%s %s: same pixels, different encoding\n", result, name)
+	} else {
+		fmt.Printf("%s %s: %d of %d pixels (%.2f%%) differ by more than %d, maximum difference %d; see %s\n",
+			result, name, c.Differing, c.Pixels, 100*c.Fraction(), *thresholdFlag, c.MaxDelta, diffName)
+	}
+	return ok, diffName, nil
+}
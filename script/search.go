@@ -0,0 +1,78 @@
+// Copyright 2026 Nigel Tao.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build ignore
+
+package main
+
+// search.go searches the blog posts, using the search-index.json file that
+// update.go generates. It prints the best matching posts and sections, best
+// first. For example:
+//
+//	go run script/search.go repeat offsets
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/nigeltao/nigeltao.github.io/lib/search"
+)
+
+var (
+	indexFlag = flag.String("index", "search-index.json", "the search index file")
+	nFlag     = flag.Int("n", 10, "the maximum number of results")
+)
+
+func main() {
+	flag.Parse()
+	if err := main1(); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+
+func main1() error {
+	query := strings.Join(flag.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		return errors.New("usage: go run script/search.go [-index=filename] [-n=count] query terms")
+	}
+
+	src, err := ioutil.ReadFile(*indexFlag)
+	if err != nil {
+		return err
+	}
+	idx := &search.Index{}
+	if err := json.Unmarshal(src, idx); err != nil {
+		return fmt.Errorf("%s: %v", *indexFlag, err)
+	}
+
+	hits := idx.Search(query, *nFlag)
+	if len(hits) == 0 {
+		return fmt.Errorf("no results for %q", query)
+	}
+	for i := range hits {
+		h := &hits[i]
+		title := h.Post.Title
+		if h.Section.Heading != "" {
+			title += " » " + h.Section.Heading
+		}
+		fmt.Printf("%2d. %s (%.2f)\n    %s\n", i+1, title, h.Score, h.URL())
+	}
+	return nil
+}
@@ -16,127 +16,2475 @@
 
 package main
 
-// update.go updates the blog posts listed in README.md.
+// update.go updates the blog posts listed in README.md and renders each post
+// (and README.md itself) as HTML.
+//
+// With the -check flag, it doesn't write anything. Instead, it prints a
+// unified diff of what it would change and fails if anything is stale. This
+// is suitable for a pre-commit hook.
+//
+// With the -serve flag, it runs a local web server for previewing the site,
+// rebuilding it (in memory) whenever a blog post changes and reloading any
+// open browser tabs.
+//
+// With the -checklinks flag, it instead checks the posts' relative links and
+// images, reporting any that are broken and any assets that are unused (other
+// than those listed in script/site.json's keptAssets).
+//
+// With the -epub=filename flag, it instead writes an EPUB e-book, for reading
+// offline, of the -series=name series or of the blog posts named by the
+// command line arguments. For example:
+//
+//	go run script/update.go -epub=zstd.epub -series="Zstandard Worked Example"
+//
+// With the -lint flag, it instead explains why each Markdown file under ./blog
+// is or isn't a blog post and reports likely authoring mistakes, such as
+// images without alt text.
+//
+// The site's URL, title, author, README.md section markers and generated file
+// names are configured by script/site.json (or the -config file).
+//
+// Posts marked "Draft: true" are left out (of README.md, the feeds, etc.)
+// unless the -drafts flag is given. Posts published after today, or after the
+// -now date, are also left out until that date, so that a scheduled post can
+// be committed ahead of time.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "image/gif"
+
+	"github.com/nigeltao/nigeltao.github.io/lib/atom"
+	"github.com/nigeltao/nigeltao.github.io/lib/card"
+	"github.com/nigeltao/nigeltao.github.io/lib/diff"
+	"github.com/nigeltao/nigeltao.github.io/lib/epub"
+	"github.com/nigeltao/nigeltao.github.io/lib/highlight"
+	"github.com/nigeltao/nigeltao.github.io/lib/markdown"
+	"github.com/nigeltao/nigeltao.github.io/lib/preview"
+	"github.com/nigeltao/nigeltao.github.io/lib/search"
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+var (
+	configFlag = flag.String("config", "script/site.json", "the site configuration file")
+	checkFlag  = flag.Bool("check", false,
+		"report (as a diff) any stale generated files instead of updating them")
+	checkLinksFlag = flag.Bool("checklinks", false,
+		"check links and assets instead of updating the generated files")
+	epubFlag = flag.String("epub", "",
+		"write an EPUB e-book (of -series or of the posts named as arguments) to this file, instead of updating the generated files")
+	seriesFlag = flag.String("series", "",
+		"the title or slug of the series to write, for -epub")
+	lintFlag = flag.Bool("lint", false,
+		"check the blog posts for authoring mistakes instead of updating the generated files")
+	serveFlag = flag.Bool("serve", false,
+		"serve a live preview of the site instead of updating the generated files")
+	addrFlag = flag.String("addr", "localhost:8000",
+		"the address to serve on, for -serve")
+	draftsFlag = flag.Bool("drafts", false,
+		"include draft posts")
+	nowFlag = flag.String("now", "",
+		"the date (YYYY-MM-DD) to publish future-dated posts as of (default today)")
+)
+
+func main() {
+	flag.Parse()
+	if err := main1(); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+
+func main1() error {
+	if *nowFlag == "" {
+		*nowFlag = time.Now().Format("2006-01-02")
+	} else if !isDate(*nowFlag) {
+		return fmt.Errorf("invalid -now date %q", *nowFlag)
+	}
+	if err := loadSiteConfig(*configFlag); err != nil {
+		return err
+	}
+
+	if *checkLinksFlag {
+		return checkLinks()
+	} else if *lintFlag {
+		return lint()
+	} else if *epubFlag != "" {
+		return writeEPUB(*epubFlag)
+	} else if *serveFlag {
+		return serve()
+	}
+
+	out := newOutputs(nil)
+	if err := build(out); err != nil {
+		return err
+	}
+	if *checkFlag {
+		return out.check()
+	}
+	return out.flush()
+}
+
+// build generates the web site's files (in memory).
+func build(out *outputs) error {
+	posts, err := findBlogPosts()
+	if err != nil {
+		return err
+	}
+	if len(posts) == 0 {
+		return errors.New("no blog posts")
+	}
+	allSeries, err := findSeries(posts)
+	if err != nil {
+		return err
+	}
+	archives, err := findArchives(posts)
+	if err != nil {
+		return err
+	}
+
+	entries, err := newFeedEntries(out, posts)
+	if err != nil {
+		return err
+	}
+	if err := writeFeed(out, entries); err != nil {
+		return err
+	}
+	if err := writeJSONFeed(out, entries); err != nil {
+		return err
+	}
+	if err := writeSitemap(out, posts, allSeries, archives); err != nil {
+		return err
+	}
+	if err := writeSearchIndex(out, posts); err != nil {
+		return err
+	}
+	projects, err := loadProjects(posts)
+	if err != nil {
+		return err
+	}
+	if err := writeReadme(out, posts, allSeries, archives, projects); err != nil {
+		return err
+	}
+	if err := writeSeries(out, allSeries); err != nil {
+		return err
+	}
+	if err := writeArchives(out, archives); err != nil {
+		return err
+	}
+	links, err := findPostLinks(out, posts)
+	if err != nil {
+		return err
+	}
+	if err := writeHTML(out, posts, allSeries, archives, links); err != nil {
+		return err
+	}
+	writeCardSums(out)
+	return nil
+}
+
+// serve runs a local web server for previewing the site. It rebuilds the site
+// when a blog post (or README.md or the HTML template) changes. Successive
+// builds share a cache of parsed Markdown files, so that only the changed
+// files are parsed again.
+func serve() error {
+	cache := map[string]*cachedMarkdown{}
+	watch := []string{"README.md", "blog", "script/template.html"}
+	if site.ProjectsFile != "" {
+		watch = append(watch, site.ProjectsFile)
+	}
+	return preview.ListenAndServe(*addrFlag, watch, func() (map[string][]byte, error) {
+		out := newOutputs(cache)
+		if err := build(out); err != nil {
+			return nil, err
+		}
+		return out.contents, nil
+	})
+}
+
+// outputs are the generated files. They are held in memory until they have
+// all been generated, so that the -check flag can compare them with what's on
+// disk instead of writing them.
+type outputs struct {
+	filenames []string
+	contents  map[string][]byte
+
+	// markdownCache holds parsed Markdown files, keyed by filename. It may be
+	// shared by successive builds.
+	markdownCache map[string]*cachedMarkdown
+
+	// oldCardSums and cardSums map card image filenames to the hashes of what
+	// they show, as per cardSumsFilename. The old sums are what's on disk (or
+	// nil if they haven't been read yet) and the others are this build's.
+	oldCardSums map[string]string
+	cardSums    map[string]string
+}
+
+type cachedMarkdown struct {
+	src    []byte
+	isPost bool
+	title  *markdown.Node
+	doc    *markdown.Node
+}
+
+// newOutputs returns an empty set of outputs. The markdownCache may be nil, in
+// which case a new (empty) cache is used.
+func newOutputs(markdownCache map[string]*cachedMarkdown) *outputs {
+	if markdownCache == nil {
+		markdownCache = map[string]*cachedMarkdown{}
+	}
+	return &outputs{
+		contents:      map[string][]byte{},
+		markdownCache: markdownCache,
+		cardSums:      map[string]string{},
+	}
+}
+
+func (o *outputs) writeFile(filename string, contents []byte) {
+	if _, ok := o.contents[filename]; !ok {
+		o.filenames = append(o.filenames, filename)
+	}
+	o.contents[filename] = contents
+}
+
+// readFile returns the contents of filename, preferring the newly generated
+// contents (if any) to what's on disk. Some generated files, like README.md
+// and the series index pages, are inputs to other generated files.
+func (o *outputs) readFile(filename string) ([]byte, error) {
+	if contents, ok := o.contents[filename]; ok {
+		return contents, nil
+	}
+	return ioutil.ReadFile(filename)
+}
+
+// flush writes the generated files that differ from what's on disk and deletes
+// the orphaned ones.
+func (o *outputs) flush() error {
+	orphans, err := o.orphans()
+	if err != nil {
+		return err
+	}
+	for _, filename := range orphans {
+		if err := os.Remove(filename); err != nil {
+			return err
+		}
+	}
+	for _, filename := range o.filenames {
+		contents := o.contents[filename]
+		if old, err := ioutil.ReadFile(filename); (err == nil) && bytes.Equal(old, contents) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filename, contents, 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check prints a unified diff of the generated files that differ from what's
+// on disk (including the orphaned ones, which flush would delete), returning an
+// error if there are any. It doesn't write anything.
+func (o *outputs) check() error {
+	numStale := 0
+	for _, filename := range o.filenames {
+		aName := "a/" + filename
+		old, err := ioutil.ReadFile(filename)
+		if os.IsNotExist(err) {
+			aName = "/dev/null"
+		} else if err != nil {
+			return err
+		}
+		if isBinary(old) || isBinary(o.contents[filename]) {
+			if !bytes.Equal(old, o.contents[filename]) {
+				fmt.Printf("Binary files %s and %s differ\n", aName, "b/"+filename)
+				numStale++
+			}
+		} else if d := diff.Unified(aName, "b/"+filename, old, o.contents[filename]); d != nil {
+			os.Stdout.Write(d)
+			numStale++
+		}
+	}
+	orphans, err := o.orphans()
+	if err != nil {
+		return err
+	}
+	for _, filename := range orphans {
+		old, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		if isBinary(old) {
+			fmt.Printf("Binary files %s and /dev/null differ\n", "a/"+filename)
+		} else {
+			os.Stdout.Write(diff.Unified("a/"+filename, "/dev/null", old, nil))
+		}
+		numStale++
+	}
+	if numStale > 0 {
+		return fmt.Errorf("%d generated file(s) are stale: run \"go run script/update.go\"", numStale)
+	}
+	return nil
+}
+
+// generatedDirs are the directories that hold only generated files. Other
+// generated files, such as the blog posts' HTML pages, sit alongside
+// hand-written ones.
+var generatedDirs = []string{"blog/cards", "blog/series", "blog/tags"}
+
+// orphans returns the files in the generatedDirs that are no longer generated,
+// such as the tag page for a tag that no post has any more or the card image
+// for a deleted post. Flushing the outputs deletes them.
+func (o *outputs) orphans() (ret []string, _ error) {
+	for _, dir := range generatedDirs {
+		err := filepath.Walk(dir, func(filename string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			filename = filepath.ToSlash(filename)
+			if _, ok := o.contents[filename]; !ok && !info.IsDir() {
+				ret = append(ret, filename)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// isBinary returns whether a file's contents, such as a PNG image, are binary
+// instead of text. Binary files contain NUL bytes and text files don't.
+func isBinary(contents []byte) bool {
+	return bytes.IndexByte(contents, 0) >= 0
+}
+
+// siteConfig is the site configuration, loaded from script/site.json, for
+// everything that would otherwise be specific to nigeltao.github.io.
+type siteConfig struct {
+	// URL is the site's absolute URL, ending in a slash, such as
+	// "https://nigeltao.github.io/".
+	URL string `json:"url"`
+
+	// Title and Author are the site's (and its feeds') title and author.
+	Title  string `json:"title"`
+	Author string `json:"author"`
+
+	// Language is a BCP 47 language tag, such as "en".
+	Language string `json:"language"`
+
+	// ProjectsFile is the data file, such as "script/projects.json", that
+	// README.md's Projects section is generated from. It is required if (and
+	// only if) there is a Projects section.
+	ProjectsFile string `json:"projectsFile"`
+
+	// Sections are README.md's section markers. The (generated) blog post
+	// list follows the Blog heading. If not empty, the (generated) project
+	// list follows the Projects heading and the (generated) copyright line
+	// starts with the Copyright word.
+	Sections struct {
+		Blog      string `json:"blog"`
+		Projects  string `json:"projects"`
+		Copyright string `json:"copyright"`
+	} `json:"sections"`
+
+	// Outputs are the names of the generated files, other than README.md and
+	// the files under ./blog.
+	Outputs struct {
+		AtomFeed    string `json:"atomFeed"`
+		JSONFeed    string `json:"jsonFeed"`
+		Sitemap     string `json:"sitemap"`
+		SearchIndex string `json:"searchIndex"`
+	} `json:"outputs"`
+
+	// KeptAssets are files under ./blog that nothing links to but that are
+	// kept anyway, such as the originals that other tools (not the Go
+	// programs) made a post's images from. The -checklinks flag doesn't
+	// report them as unused.
+	KeptAssets []string `json:"keptAssets"`
+}
+
+// site is the site configuration. It is loaded (by main1) before anything
+// else happens.
+var site siteConfig
+
+// loadSiteConfig loads and validates the site configuration.
+func loadSiteConfig(filename string) error {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.DisallowUnknownFields()
+	c := siteConfig{}
+	if err := dec.Decode(&c); err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	if u, err := url.Parse(c.URL); (err != nil) || ((u.Scheme != "http") && (u.Scheme != "https")) ||
+		(u.Host == "") || !strings.HasSuffix(u.Path, "/") || (u.RawQuery != "") || (u.Fragment != "") {
+		return fmt.Errorf("%s: invalid url %q: it should be absolute and end with a slash", filename, c.URL)
+	}
+	for _, f := range [...]struct {
+		name  string
+		value string
+	}{
+		{"title", c.Title},
+		{"author", c.Author},
+		{"language", c.Language},
+	} {
+		if strings.TrimSpace(f.value) == "" {
+			return fmt.Errorf("%s: missing %s", filename, f.name)
+		}
+	}
+
+	for _, f := range [...]struct {
+		name     string
+		value    string
+		optional bool
+	}{
+		{"sections.blog", c.Sections.Blog, false},
+		{"sections.projects", c.Sections.Projects, true},
+		{"sections.copyright", c.Sections.Copyright, true},
+	} {
+		if f.value == "" {
+			if !f.optional {
+				return fmt.Errorf("%s: missing %s", filename, f.name)
+			}
+		} else if (f.value != strings.TrimSpace(f.value)) || strings.Contains(f.value, "\n") {
+			return fmt.Errorf("%s: invalid %s %q: it should be a single line", filename, f.name, f.value)
+		} else if (f.name != "sections.copyright") && !strings.HasPrefix(f.value, "#") {
+			return fmt.Errorf("%s: invalid %s %q: it should be a Markdown heading", filename, f.name, f.value)
+		}
+	}
+
+	if (c.Sections.Projects != "") && (c.ProjectsFile == "") {
+		return fmt.Errorf("%s: missing projectsFile for the %q section", filename, c.Sections.Projects)
+	} else if (c.Sections.Projects == "") && (c.ProjectsFile != "") {
+		return fmt.Errorf("%s: projectsFile %q but no sections.projects", filename, c.ProjectsFile)
+	}
+
+	seen := map[string]string{}
+	for _, f := range [...]struct {
+		name  string
+		value string
+	}{
+		{"outputs.atomFeed", c.Outputs.AtomFeed},
+		{"outputs.jsonFeed", c.Outputs.JSONFeed},
+		{"outputs.sitemap", c.Outputs.Sitemap},
+		{"outputs.searchIndex", c.Outputs.SearchIndex},
+	} {
+		if (f.value == "") || (f.value != path.Clean(f.value)) || path.IsAbs(f.value) ||
+			strings.HasPrefix(f.value, "../") || strings.Contains(f.value, `\`) ||
+			(f.value == "README.md") || strings.HasPrefix(f.value, "blog/") {
+			return fmt.Errorf("%s: invalid %s %q", filename, f.name, f.value)
+		} else if other := seen[f.value]; other != "" {
+			return fmt.Errorf("%s: %s and %s are both %q", filename, other, f.name, f.value)
+		}
+		seen[f.value] = f.name
+	}
+
+	for _, k := range c.KeptAssets {
+		if (k != path.Clean(k)) || !strings.HasPrefix(k, "blog/") || strings.Contains(k, `\`) {
+			return fmt.Errorf("%s: invalid keptAssets entry %q: it should be a file under blog/", filename, k)
+		}
+	}
+
+	site = c
+	return nil
+}
+
+// Host returns the host part of the site's URL, such as
+// "nigeltao.github.io".
+func (c *siteConfig) Host() string {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return c.URL
+	}
+	return u.Host
+}
+
+// feedEntry is a blog post as it appears in the feeds. The Atom and JSON Feed
+// writers both work from the same entries, so that the two feeds agree.
+type feedEntry struct {
+	id        string
+	url       string
+	title     string // Plain text.
+	titleHTML string
+	published string // RFC 3339.
+	updated   string // RFC 3339.
+	tags      []string
+	summary   string // Plain text.
+	content   string // HTML.
+}
+
+// newFeedEntry loads and renders a blog post for the feed. Its links and
+// images are made absolute and Markdown syntax in its title, such as
+// backticks, is converted to HTML (or, for plain text, removed).
+func newFeedEntry(out *outputs, p *blogPost) (feedEntry, error) {
+	title, body, err := loadMarkdown(out, p.filename, true)
+	if err != nil {
+		return feedEntry{}, err
+	} else if title == nil {
+		return feedEntry{}, fmt.Errorf("%s: no title", p.filename)
+	}
+	r := &markdown.Renderer{RewriteURL: absoluteURL(p.filename)}
+	titleHTML := bytes.NewBuffer(nil)
+	r.RenderChildren(titleHTML, title)
+	content := bytes.NewBuffer(nil)
+	r.Render(content, body)
+
+	summary := p.summary
+	if summary == "" {
+		summary = summarize(body)
+	}
+	u := site.URL + htmlFilename(p.filename)
+	return feedEntry{
+		id:        u,
+		url:       u,
+		title:     markdown.PlainText(title),
+		titleHTML: titleHTML.String(),
+		published: rfc3339Date(p.date),
+		updated:   rfc3339Date(p.lastModified()),
+		tags:      p.tags,
+		summary:   summary,
+		content:   content.String(),
+	}, nil
+}
+
+// rfc3339Date converts a "2006-01-02" date to the RFC 3339 format that feeds
+// use.
+func rfc3339Date(date string) string {
+	return date + "T00:00:00+00:00"
+}
+
+// newFeedEntries returns the feed entries for the blog posts, newest first.
+func newFeedEntries(out *outputs, posts []blogPost) ([]feedEntry, error) {
+	entries := make([]feedEntry, 0, len(posts))
+	for i := len(posts) - 1; i >= 0; i-- {
+		e, err := newFeedEntry(out, &posts[i])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// writeFeed writes the Atom feed.
+func writeFeed(out *outputs, entries []feedEntry) error {
+	updated := ""
+	for i := range entries {
+		if u := entries[i].updated; u > updated {
+			updated = u
+		}
+	}
+
+	dst := bytes.NewBuffer(nil)
+	feedURL := site.URL + site.Outputs.AtomFeed
+	dst.WriteString(`<feed xmlns="http://www.w3.org/2005/Atom">` + "\n")
+	fmt.Fprintf(dst, `  <link href="%s" rel="self" type="application/atom+xml"/>`+"\n",
+		xmlAttrEscape(feedURL))
+	fmt.Fprintf(dst, `  <link href="%s" rel="alternate" type="text/html"/>`+"\n",
+		xmlAttrEscape(site.URL))
+	fmt.Fprintf(dst, `  <updated>%s</updated>`+"\n", xmlEscape(updated))
+	fmt.Fprintf(dst, `  <id>%s</id>`+"\n", xmlEscape(feedURL))
+	fmt.Fprintf(dst, `  <title type="text">%s</title>`+"\n", xmlEscape(site.Title))
+	fmt.Fprintf(dst, `  <author><name>%s</name></author>`+"\n", xmlEscape(site.Author))
+	for i := range entries {
+		writeFeed1(dst, &entries[i])
+	}
+	dst.WriteString("</feed>\n")
+
+	if err := atom.Validate(dst.Bytes()); err != nil {
+		return fmt.Errorf("%s: %v", site.Outputs.AtomFeed, err)
+	}
+	out.writeFile(site.Outputs.AtomFeed, dst.Bytes())
+	return nil
+}
+
+// writeFeed1 writes an Atom entry. Every value is escaped: text and HTML go
+// in element content and plain text goes in attributes.
+func writeFeed1(dst *bytes.Buffer, e *feedEntry) {
+	dst.WriteString("  <entry>\n")
+	fmt.Fprintf(dst, `    <title type="html">%s</title>`+"\n", xmlEscape(e.titleHTML))
+	fmt.Fprintf(dst, `    <link href="%s" rel="alternate" type="text/html" title="%s"/>`+"\n",
+		xmlAttrEscape(e.url), xmlAttrEscape(e.title))
+	fmt.Fprintf(dst, `    <published>%s</published>`+"\n", xmlEscape(e.published))
+	fmt.Fprintf(dst, `    <updated>%s</updated>`+"\n", xmlEscape(e.updated))
+	fmt.Fprintf(dst, `    <id>%s</id>`+"\n", xmlEscape(e.id))
+	for _, tag := range e.tags {
+		fmt.Fprintf(dst, `    <category term="%s"/>`+"\n", xmlAttrEscape(tag))
+	}
+	fmt.Fprintf(dst, `    <summary type="text">%s</summary>`+"\n", xmlEscape(e.summary))
+	fmt.Fprintf(dst, `    <content type="html">%s</content>`+"\n", xmlEscape(e.content))
+	dst.WriteString("  </entry>\n")
+}
+
+// writeSitemap lists the site's pages, so that search engines can find them
+// all: the home page, the blog posts and the series and archive index pages.
+func writeSitemap(out *outputs, posts []blogPost, allSeries []*series, archives []*archive) error {
+	dst := bytes.NewBuffer(nil)
+	dst.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	dst.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n")
+
+	updated := ""
+	for i := range posts {
+		if u := posts[i].lastModified(); u > updated {
+			updated = u
+		}
+	}
+	writeSitemap1(dst, "", updated)
+
+	for i := len(posts) - 1; i >= 0; i-- {
+		p := &posts[i]
+		writeSitemap1(dst, htmlFilename(p.filename), p.lastModified())
+	}
+
+	for _, ser := range allSeries {
+		updated := ""
+		for _, p := range ser.parts {
+			if u := p.lastModified(); u > updated {
+				updated = u
+			}
+		}
+		writeSitemap1(dst, htmlFilename(ser.filename()), updated)
+	}
+
+	for _, a := range archives {
+		updated := ""
+		for _, p := range a.posts {
+			if u := p.lastModified(); u > updated {
+				updated = u
+			}
+		}
+		writeSitemap1(dst, htmlFilename(a.filename), updated)
+	}
+
+	dst.WriteString("</urlset>\n")
+	out.writeFile(site.Outputs.Sitemap, dst.Bytes())
+	return nil
+}
+
+func writeSitemap1(dst *bytes.Buffer, filename string, lastmod string) {
+	fmt.Fprintf(dst, "  <url><loc>%s</loc><lastmod>%s</lastmod></url>\n",
+		xmlEscape(site.URL+filename), xmlEscape(lastmod))
+}
+
+// jsonFeed and jsonFeedItem are a JSON Feed 1.1 document, as per
+// https://www.jsonfeed.org/version/1.1/
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	Language    string           `json:"language"`
+	Authors     []jsonFeedAuthor `json:"authors"`
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	Title         string   `json:"title"`
+	ContentHTML   string   `json:"content_html,omitempty"`
+	Summary       string   `json:"summary,omitempty"`
+	DatePublished string   `json:"date_published"`
+	DateModified  string   `json:"date_modified"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+// writeJSONFeed writes the JSON Feed equivalent of the Atom feed.
+func writeJSONFeed(out *outputs, entries []feedEntry) error {
+	f := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       site.Title,
+		HomePageURL: site.URL,
+		FeedURL:     site.URL + site.Outputs.JSONFeed,
+		Language:    site.Language,
+		Authors:     []jsonFeedAuthor{{Name: site.Author}},
+		Items:       make([]jsonFeedItem, 0, len(entries)),
+	}
+	for i := range entries {
+		e := &entries[i]
+		f.Items = append(f.Items, jsonFeedItem{
+			ID:            e.id,
+			URL:           e.url,
+			Title:         e.title,
+			ContentHTML:   e.content,
+			Summary:       e.summary,
+			DatePublished: e.published,
+			DateModified:  e.updated,
+			Tags:          e.tags,
+		})
+	}
+
+	dst := bytes.NewBuffer(nil)
+	enc := json.NewEncoder(dst)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(&f); err != nil {
+		return fmt.Errorf("%s: %v", site.Outputs.JSONFeed, err)
+	}
+	out.writeFile(site.Outputs.JSONFeed, dst.Bytes())
+	return nil
+}
+
+// writeSearchIndex writes a full-text search index of the blog posts, for use
+// by script/search.go. Each post is indexed section by section, where each
+// heading starts a new section, so that search results can link to the
+// relevant part of a long post.
+func writeSearchIndex(out *outputs, posts []blogPost) error {
+	b := search.NewBuilder()
+	for i := range posts {
+		p := &posts[i]
+		title, doc, err := loadMarkdown(out, p.filename, true)
+		if err != nil {
+			return err
+		}
+		ids := headingIDs(title, doc)
+		titleText := ""
+		if title != nil {
+			titleText = markdown.PlainText(title)
+		}
+		post := b.AddPost(search.Post{
+			Title: titleText,
+			URL:   site.URL + htmlFilename(p.filename),
+		})
+
+		anchor, heading := "", ""
+		text := &strings.Builder{}
+		text.WriteString(titleText)
+		for _, c := range doc.Children {
+			if c.Kind == markdown.KindHeading {
+				b.AddSection(post, anchor, heading, text.String())
+				anchor, heading = ids[c], markdown.PlainText(c)
+				text.Reset()
+				continue
+			}
+			text.WriteByte('\n')
+			text.WriteString(markdown.PlainText(c))
+		}
+		b.AddSection(post, anchor, heading, text.String())
+	}
+
+	dst, err := marshalSearchIndex(b.Index())
+	if err != nil {
+		return fmt.Errorf("%s: %v", site.Outputs.SearchIndex, err)
+	}
+	out.writeFile(site.Outputs.SearchIndex, dst)
+	return nil
+}
+
+// marshalSearchIndex is like json.Marshal but puts each post, section and
+// term on its own line, so that editing one post doesn't make a one line diff
+// of the whole (large) file. It doesn't use json.MarshalIndent, which would
+// put every number in every term's postings on its own line.
+func marshalSearchIndex(idx *search.Index) ([]byte, error) {
+	var marshalErr error
+	marshal := func(v interface{}) []byte {
+		b, err := json.Marshal(v)
+		if (err != nil) && (marshalErr == nil) {
+			marshalErr = err
+		}
+		return b
+	}
+
+	posts := make([][]byte, 0, len(idx.Posts))
+	for _, p := range idx.Posts {
+		posts = append(posts, marshal(p))
+	}
+	sections := make([][]byte, 0, len(idx.Sections))
+	for _, s := range idx.Sections {
+		sections = append(sections, marshal(s))
+	}
+	keys := make([]string, 0, len(idx.Terms))
+	for k := range idx.Terms {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	terms := make([][]byte, 0, len(keys))
+	for _, k := range keys {
+		terms = append(terms, append(append(marshal(k), ':'), marshal(idx.Terms[k])...))
+	}
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+
+	dst := bytes.NewBuffer(nil)
+	writeLines := func(open string, lines [][]byte, close string) {
+		dst.WriteString(open)
+		for i, line := range lines {
+			if i > 0 {
+				dst.WriteByte(',')
+			}
+			dst.WriteByte('\n')
+			dst.Write(line)
+		}
+		dst.WriteByte('\n')
+		dst.WriteString(close)
+	}
+	writeLines(`{"posts":[`, posts, "]")
+	writeLines(`,"sections":[`, sections, "]")
+	writeLines(`,"terms":{`, terms, "}")
+	dst.WriteString("}\n")
+	return dst.Bytes(), nil
+}
+
+// writeReadme regenerates README.md's list of blog posts (which follows the
+// Blog section marker), its list of projects (which follows the Projects
+// section marker) and its copyright line (which starts with the Copyright
+// section marker). Each list runs to the next heading. Everything else is
+// kept as is, as are the sections whose markers aren't configured. It is an
+// error for a configured section marker to be missing.
+func writeReadme(out *outputs, posts []blogPost, allSeries []*series, archives []*archive, projects []*project) error {
+	dst := bytes.NewBuffer(nil)
+
+	src, err := out.readFile("README.md")
+	if err != nil {
+		return err
+	}
+
+	blog := "\n\n" + site.Sections.Blog + "\n\n"
+	src, ok := match(dst, src, blog)
+	if !ok {
+		return fmt.Errorf("README.md: no %q section marker", site.Sections.Blog)
+	}
+
+	dst.WriteString(blog)
+	fmt.Fprintf(dst, "[RSS/Atom feed](/%s).\n\n", site.Outputs.AtomFeed)
+	writeArchiveLinks(dst, archives, archiveYear, "By year", nil, "./blog/")
+	writeArchiveLinks(dst, archives, archiveTag, "By tag", nil, "./blog/")
+	for i := range posts {
+		p := &posts[i]
+		if p.series == "" {
+			writeReadme1(dst, "- ", p, p.title)
+			continue
+		}
+
+		// List a series' parts together, nested under the series' entry,
+		// which is placed where its earliest part would be.
+		ser := findSeriesByName(allSeries, p.series)
+		if p != ser.first() {
+			continue
+		}
+		fmt.Fprintf(dst, "- %s [%s](./%s) (%d parts)\n",
+			p.date, ser.title, ser.filename(), len(ser.parts))
+		for _, q := range ser.parts {
+			writeReadme1(dst, "  - ", q, q.partTitle())
+		}
+	}
+
+	// Drop the old list of blog posts, up to the next heading (or copyright
+	// line), and likewise the old list of projects (if any). Keep everything
+	// else, including all of what follows the blog posts when the other
+	// sections are disabled.
+	src = src[skipGenerated(src, len(blog)):]
+	if site.Sections.Projects != "" {
+		proj := "\n\n" + site.Sections.Projects + "\n\n"
+		if src, ok = match(dst, src, proj); !ok {
+			return fmt.Errorf("README.md: no %q section marker after %q", site.Sections.Projects, site.Sections.Blog)
+		}
+		src = src[skipGenerated(src, len(proj)):]
+		dst.WriteString(proj)
+		writeProjects(dst, posts, projects)
+	}
+
+	if site.Sections.Copyright != "" {
+		copr := "\n\n" + site.Sections.Copyright + " "
+		if src, ok = match(dst, src, copr); !ok {
+			return fmt.Errorf("README.md: no %q section marker at the end", site.Sections.Copyright)
+		}
+		fmt.Fprintf(dst, "\n\n%s %s-%s %s\n",
+			site.Sections.Copyright,
+			posts[0].date[:4],
+			posts[len(posts)-1].date[:4],
+			site.Author,
+		)
+	} else {
+		dst.Write(src)
+	}
+
+	out.writeFile("README.md", dst.Bytes())
+	return nil
+}
+
+// skipGenerated returns where a generated section of README.md ends: at the
+// first heading or copyright line at or after src[i:].
+func skipGenerated(src []byte, i int) int {
+	end := len(src)
+	if j := bytes.Index(src[i:], []byte("\n\n#")); j >= 0 {
+		end = i + j
+	}
+	if c := site.Sections.Copyright; c != "" {
+		if j := bytes.Index(src[i:], []byte("\n\n"+c+" ")); (j >= 0) && ((i + j) < end) {
+			end = i + j
+		}
+	}
+	return end
+}
+
+// writeProjects writes README.md's list of projects. Each project's related
+// blog posts are listed, oldest first, nested under the project's entry.
+func writeProjects(dst *bytes.Buffer, posts []blogPost, projects []*project) {
+	for _, proj := range projects {
+		fmt.Fprintf(dst, "- [%s](%s), %s", proj.Name, proj.URL, proj.Description)
+		if proj.Status != "active" {
+			fmt.Fprintf(dst, " (%s)", proj.Status)
+		}
+		dst.WriteString("\n")
+
+		related := map[string]bool{}
+		for _, filename := range proj.Posts {
+			related[filename] = true
+		}
+		for i := range posts {
+			if p := &posts[i]; related[p.filename] {
+				writeReadme1(dst, "  - ", p, p.title)
+			}
+		}
+	}
+}
+
+func writeReadme1(dst *bytes.Buffer, prefix string, p *blogPost, title string) {
+	fmt.Fprintf(dst, "%s%s [%s](./%s)", prefix, p.date, title, p.filename)
+	if p.updated != "" {
+		fmt.Fprintf(dst, " (updated %s)", p.updated)
+	}
+	dst.WriteString("\n")
+}
+
+// project is an entry in README.md's list of projects, as loaded from the
+// site's ProjectsFile.
+type project struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+
+	// Status is one of projectStatuses. Active projects' statuses aren't
+	// shown.
+	Status string `json:"status"`
+
+	// Posts are the Markdown file names, such as
+	// "blog/2022/wuffs-bzip2-decoder.md", of the blog posts about the project.
+	Posts []string `json:"posts"`
+}
+
+var projectStatuses = map[string]bool{
+	"active":       true,
+	"experimental": true,
+	"maintenance":  true,
+	"archived":     true,
+}
+
+// loadProjects loads and validates the site's ProjectsFile, if any. Each
+// project's related posts must be blog posts. Posts that aren't published
+// (drafts and future-dated posts) are dropped.
+func loadProjects(posts []blogPost) ([]*project, error) {
+	filename := site.ProjectsFile
+	if filename == "" {
+		return nil, nil
+	}
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.DisallowUnknownFields()
+	data := struct {
+		Projects []*project `json:"projects"`
+	}{}
+	if err := dec.Decode(&data); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	published := map[string]bool{}
+	for i := range posts {
+		published[posts[i].filename] = true
+	}
+	names := map[string]bool{}
+	for _, proj := range data.Projects {
+		if proj.Name == "" {
+			return nil, fmt.Errorf("%s: project with no name", filename)
+		} else if names[proj.Name] {
+			return nil, fmt.Errorf("%s: duplicate project %q", filename, proj.Name)
+		} else if u, err := url.Parse(proj.URL); (err != nil) || !u.IsAbs() {
+			return nil, fmt.Errorf("%s: %s: invalid url %q", filename, proj.Name, proj.URL)
+		} else if strings.TrimSpace(proj.Description) == "" {
+			return nil, fmt.Errorf("%s: %s: no description", filename, proj.Name)
+		} else if !projectStatuses[proj.Status] {
+			return nil, fmt.Errorf("%s: %s: invalid status %q", filename, proj.Name, proj.Status)
+		}
+		names[proj.Name] = true
+
+		kept := proj.Posts[:0]
+		for _, p := range proj.Posts {
+			if published[p] {
+				kept = append(kept, p)
+			} else if _, err := load(p); errors.Is(err, errNotABlogPost) || os.IsNotExist(err) {
+				return nil, fmt.Errorf("%s: %s: %s is not a blog post", filename, proj.Name, p)
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		proj.Posts = kept
+	}
+	return data.Projects, nil
+}
+
+// series is a multi-part sequence of blog posts, such as the "Zstandard Worked
+// Example".
+type series struct {
+	title string
+	slug  string
+
+	// parts[i] is part number (i+1).
+	parts []*blogPost
+}
+
+// filename is the name of the series' index page, which lists its parts.
+func (s *series) filename() string {
+	return "blog/series/" + s.slug + ".md"
+}
+
+// first returns the series' earliest published part. This is usually, but
+// not necessarily, part 1.
+func (s *series) first() *blogPost {
+	ret := s.parts[0]
+	for _, p := range s.parts[1:] {
+		if p.date < ret.date {
+			ret = p
+		}
+	}
+	return ret
+}
+
+// findSeries groups the posts that are part of a series. It returns an error
+// if any series' part numbers are duplicated or not contiguous from 1. A part
+// that is a draft or scheduled for later can't be skipped over, as the parts
+// link to each other by number, so the error for such a gap says why the part
+// is missing.
+func findSeries(posts []blogPost) (allSeries []*series, _ error) {
+	m := map[string]*series{}
+	for i := range posts {
+		p := &posts[i]
+		if p.series == "" {
+			continue
+		}
+		ser := m[p.series]
+		if ser == nil {
+			ser = &series{
+				title: p.series,
+				slug:  slugify(p.series),
+			}
+			m[p.series] = ser
+			allSeries = append(allSeries, ser)
+		}
+		for len(ser.parts) < p.part {
+			ser.parts = append(ser.parts, nil)
+		}
+		if q := ser.parts[p.part-1]; q != nil {
+			return nil, fmt.Errorf("series %q: part %d is both %s and %s",
+				ser.title, p.part, q.filename, p.filename)
+		}
+		ser.parts[p.part-1] = p
+	}
+
+	slugs := map[string]bool{}
+	for _, ser := range allSeries {
+		for i, p := range ser.parts {
+			if p == nil {
+				return nil, fmt.Errorf("series %q: missing part %d%s", ser.title, i+1, heldBackPart(ser.title, i+1))
+			}
+		}
+		if slugs[ser.slug] {
+			return nil, fmt.Errorf("series %q: duplicate slug %q", ser.title, ser.slug)
+		}
+		slugs[ser.slug] = true
+	}
+	return allSeries, nil
+}
+
+// heldBackPart returns why a series' part is missing from the published
+// posts, such as " (blog/2022/foo.md is a draft)", or "" if there's no such
+// part at all.
+func heldBackPart(seriesTitle string, part int) string {
+	filenames, _ := filepath.Glob("blog/*/*.md")
+	for _, filename := range filenames {
+		p, err := load(filepath.ToSlash(filename))
+		if (err != nil) || (p.series != seriesTitle) || (p.part != part) {
+			continue
+		} else if p.draft && !*draftsFlag {
+			return fmt.Sprintf(" (%s is a draft)", p.filename)
+		} else if p.date > *nowFlag {
+			return fmt.Sprintf(" (%s is scheduled for %s)", p.filename, p.date)
+		}
+	}
+	return ""
+}
+
+func findSeriesByName(allSeries []*series, name string) *series {
+	for _, ser := range allSeries {
+		if ser.title == name {
+			return ser
+		}
+	}
+	return nil
+}
+
+// writeSeries writes an index page for each series.
+func writeSeries(out *outputs, allSeries []*series) error {
+	for _, ser := range allSeries {
+		first, last := ser.first(), ser.parts[0]
+		for _, p := range ser.parts {
+			if p.date > last.date {
+				last = p
+			}
+		}
+
+		dst := bytes.NewBuffer(nil)
+		fmt.Fprintf(dst, "# %s\n\n", ser.title)
+		fmt.Fprintf(dst, "A series of %d blog posts, published from %s to %s.\n\n",
+			len(ser.parts), first.date, last.date)
+		for i, p := range ser.parts {
+			fmt.Fprintf(dst, "%d. [%s](../%s) (%s)\n",
+				i+1, p.partTitle(), strings.TrimPrefix(p.filename, "blog/"), p.date)
+		}
+
+		out.writeFile(ser.filename(), dst.Bytes())
+	}
+	return nil
+}
+
+// archive is an index page listing every blog post from a particular year or
+// with a particular tag. Year archives live alongside the posts, such as
+// "blog/2021/README.md", so that they are served as "/blog/2021/".
+type archive struct {
+	kind     archiveKind
+	name     string
+	filename string
+	posts    []*blogPost
+}
+
+type archiveKind uint8
+
+const (
+	archiveYear archiveKind = iota
+	archiveTag
+)
+
+// findArchives groups the blog posts by year (which is the directory they're
+// in) and by tag. The year archives come first, oldest first, followed by the
+// tag archives, sorted by name.
+func findArchives(posts []blogPost) (archives []*archive, _ error) {
+	years := map[string]*archive{}
+	tags := map[string]*archive{}
+	tagNames := []string(nil)
+	for i := range posts {
+		p := &posts[i]
+
+		dir := path.Dir(p.filename)
+		year := years[dir]
+		if year == nil {
+			year = &archive{
+				kind:     archiveYear,
+				name:     path.Base(dir),
+				filename: dir + "/README.md",
+			}
+			years[dir] = year
+			archives = append(archives, year)
+		}
+		year.posts = append(year.posts, p)
+
+		for _, t := range p.tags {
+			tag := tags[t]
+			if tag == nil {
+				tag = &archive{
+					kind:     archiveTag,
+					name:     t,
+					filename: "blog/tags/" + slugify(t) + ".md",
+				}
+				tags[t] = tag
+				tagNames = append(tagNames, t)
+			}
+			tag.posts = append(tag.posts, p)
+		}
+	}
+
+	sort.Slice(archives, func(i int, j int) bool {
+		return archives[i].filename < archives[j].filename
+	})
+	sort.Strings(tagNames)
+	filenames := map[string]string{}
+	for _, t := range tagNames {
+		tag := tags[t]
+		if other, ok := filenames[tag.filename]; ok {
+			return nil, fmt.Errorf("tags %q and %q: duplicate slug %q", other, t, slugify(t))
+		}
+		filenames[tag.filename] = t
+		archives = append(archives, tag)
+	}
+	return archives, nil
+}
+
+// writeArchives writes an index page for each year and each tag.
+func writeArchives(out *outputs, archives []*archive) error {
+	for _, a := range archives {
+		dst := bytes.NewBuffer(nil)
+		if a.kind == archiveYear {
+			fmt.Fprintf(dst, "# Blog Posts from %s\n\n", a.name)
+		} else {
+			fmt.Fprintf(dst, "# Blog Posts Tagged \"%s\"\n\n", a.name)
+		}
+		for _, p := range a.posts {
+			fmt.Fprintf(dst, "- %s [%s](../%s)", p.date, p.title, strings.TrimPrefix(p.filename, "blog/"))
+			if p.updated != "" {
+				fmt.Fprintf(dst, " (updated %s)", p.updated)
+			}
+			dst.WriteString("\n")
+		}
+		dst.WriteString("\n")
+		label := "Other years"
+		if a.kind == archiveTag {
+			label = "Other tags"
+		}
+		writeArchiveLinks(dst, archives, a.kind, label, a, "../")
+		fmt.Fprintf(dst, "[All blog posts](../../README.md#%s).\n",
+			markdown.Slug(strings.TrimLeft(site.Sections.Blog, "#")))
+
+		out.writeFile(a.filename, dst.Bytes())
+	}
+	return nil
+}
+
+// writeArchiveLinks writes a paragraph linking to each archive of the given
+// kind, other than except (which may be nil). Each link is base followed by the
+// archive's filename relative to the "blog" directory.
+func writeArchiveLinks(dst *bytes.Buffer, archives []*archive, kind archiveKind, label string, except *archive, base string) {
+	n := 0
+	for _, a := range archives {
+		if (a.kind != kind) || (a == except) {
+			continue
+		}
+		if n == 0 {
+			fmt.Fprintf(dst, "%s: ", label)
+		} else {
+			dst.WriteString(", ")
+		}
+		fmt.Fprintf(dst, "[%s](%s%s)", a.name, base, strings.TrimPrefix(a.filename, "blog/"))
+		n++
+	}
+	if n > 0 {
+		dst.WriteString(".\n\n")
+	}
+}
+
+// slugify converts a name like "XZ/LZMA Worked Example" to a file name like
+// "xz-lzma-worked-example". A '+' becomes a 'p', so that "C++" becomes "cpp".
+func slugify(name string) string {
+	sb := &strings.Builder{}
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r == '+' {
+			r = 'p'
+		}
+		if (('a' <= r) && (r <= 'z')) || (('0' <= r) && (r <= '9')) {
+			if dash && (sb.Len() > 0) {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return sb.String()
+}
+
+// htmlPage is the data passed to the script/template.html template.
+type htmlPage struct {
+	Site       *siteConfig
+	Title      string
+	TitleHTML  template.HTML
+	TitleID    string
+	Date       string
+	Updated    string
+	Tags       []string
+	Series     *htmlSeriesNav
+	Backlinks  []htmlLink
+	Related    []htmlLink
+	Body       template.HTML
+	Stylesheet template.CSS
+	Card       *htmlCard
+}
+
+// htmlCard is a blog post's Open Graph and Twitter card metadata. The URLs
+// are absolute.
+type htmlCard struct {
+	Title       string
+	Description string
+	URL         string
+	Image       string
+}
+
+// htmlSeriesNav is the previous / next navigation for a part of a series.
+type htmlSeriesNav struct {
+	Title    string
+	URL      string
+	Part     int
+	NumParts int
+	Prev     *htmlLink
+	Next     *htmlLink
+}
+
+type htmlLink struct {
+	Title string
+	URL   string
+}
+
+// writeHTML renders each blog post, and README.md as the home page, to a
+// standalone HTML file. This means that the web site doesn't depend on GitHub
+// Pages' implicit (Jekyll) Markdown rendering, which the .nojekyll file turns
+// off.
+func writeHTML(out *outputs, posts []blogPost, allSeries []*series, archives []*archive, links map[string]*postLinks) error {
+	tmpl, err := template.ParseFiles("script/template.html")
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		p := &posts[i]
+		if err := writeHTML1(out, tmpl, p.filename, p, findSeriesByName(allSeries, p.series), links[p.filename]); err != nil {
+			return err
+		}
+	}
+	for _, ser := range allSeries {
+		if err := writeHTML1(out, tmpl, ser.filename(), nil, nil, nil); err != nil {
+			return err
+		}
+	}
+	for _, a := range archives {
+		if err := writeHTML1(out, tmpl, a.filename, nil, nil, nil); err != nil {
+			return err
+		}
+	}
+	return writeHTML1(out, tmpl, "README.md", nil, nil, nil)
+}
+
+// writeHTML1 renders one Markdown file. p and links are nil if that file isn't
+// a blog post. ser is nil if it isn't part of a series.
+func writeHTML1(out *outputs, tmpl *template.Template, filename string, p *blogPost, ser *series, links *postLinks) error {
+	title, doc, err := loadMarkdown(out, filename, p != nil)
+	if err != nil {
+		return err
+	}
+	ids := headingIDs(title, doc)
+	r := &markdown.Renderer{
+		RewriteURL:    rewriteURL,
+		HighlightCode: highlightCode,
+		HeadingIDs:    ids,
+	}
+	page := htmlPage{
+		Site:       &site,
+		Stylesheet: template.CSS(highlight.Stylesheet),
+	}
+	if p != nil {
+		page.Date = p.date
+		page.Updated = p.updated
+		page.Tags = p.tags
+	}
+	if ser != nil {
+		nav := &htmlSeriesNav{
+			Title:    ser.title,
+			URL:      "/" + htmlFilename(ser.filename()),
+			Part:     p.part,
+			NumParts: len(ser.parts),
+		}
+		if p.part > 1 {
+			q := ser.parts[p.part-2]
+			nav.Prev = &htmlLink{Title: q.partTitle(), URL: "/" + htmlFilename(q.filename)}
+		}
+		if p.part < len(ser.parts) {
+			q := ser.parts[p.part]
+			nav.Next = &htmlLink{Title: q.partTitle(), URL: "/" + htmlFilename(q.filename)}
+		}
+		page.Series = nav
+	}
+	if links != nil {
+		for _, q := range links.backlinks {
+			page.Backlinks = append(page.Backlinks, htmlLink{Title: q.title, URL: "/" + htmlFilename(q.filename)})
+		}
+		for _, q := range links.related {
+			page.Related = append(page.Related, htmlLink{Title: q.title, URL: "/" + htmlFilename(q.filename)})
+		}
+	}
+
+	if title != nil {
+		buf := bytes.NewBuffer(nil)
+		r.RenderChildren(buf, title)
+		page.Title = markdown.PlainText(title)
+		page.TitleHTML = template.HTML(buf.String())
+		page.TitleID = ids[title]
+		if p != nil {
+			if page.Card, err = writeCard(out, p, page.Title, doc); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Long blog posts get a table of contents, just before their first
+	// section.
+	toc := []*markdown.Node(nil)
+	if p != nil {
+		toc = tocHeadings(doc)
+	}
+	body := bytes.NewBuffer(nil)
+	for _, c := range doc.Children {
+		if (len(toc) > tocThreshold) && (c == toc[0]) {
+			writeTOC(body, toc, ids)
+		}
+		r.Render(body, c)
+	}
+	page.Body = template.HTML(body.String())
+
+	dst := bytes.NewBuffer(nil)
+	if err := tmpl.Execute(dst, &page); err != nil {
+		return err
+	}
+	out.writeFile(htmlFilename(filename), dst.Bytes())
+	return nil
+}
+
+// postLinks are the other blog posts listed after a blog post: those that link
+// to it and those that are about similar things.
+type postLinks struct {
+	backlinks []*blogPost
+	related   []*blogPost
+}
+
+// numRelated is the maximum number of related posts listed after each post.
+// Posts less similar than minSimilarity (as per search.Similarities) aren't
+// listed.
+const (
+	numRelated    = 3
+	minSimilarity = 0.1
+)
+
+// findPostLinks finds, for each blog post, the other posts that link to it
+// (oldest first) and the posts whose text is most similar to it (most similar
+// first). Other parts of the same series are already listed after each post,
+// so they aren't also listed as related posts.
+func findPostLinks(out *outputs, posts []blogPost) (map[string]*postLinks, error) {
+	ret := map[string]*postLinks{}
+	for i := range posts {
+		ret[posts[i].filename] = &postLinks{}
+	}
+
+	texts := make([]string, len(posts))
+	for i := range posts {
+		p := &posts[i]
+		title, doc, err := loadMarkdown(out, p.filename, true)
+		if err != nil {
+			return nil, err
+		}
+		text := &strings.Builder{}
+		if title != nil {
+			text.WriteString(markdown.PlainText(title))
+		}
+		linked := map[string]bool{}
+		doc.Walk(func(n *markdown.Node) bool {
+			switch n.Kind {
+			case markdown.KindCodeBlock, markdown.KindHTMLBlock:
+				// Code isn't prose, so it doesn't count towards similarity.
+				return false
+			case markdown.KindHeading, markdown.KindParagraph, markdown.KindTableCell:
+				text.WriteByte('\n')
+				text.WriteString(markdown.PlainText(n))
+			case markdown.KindLink:
+				target, _, ok := resolveLink(p.filename, n.Destination)
+				if ok && (target != p.filename) && (ret[target] != nil) && !linked[target] {
+					linked[target] = true
+					ret[target].backlinks = append(ret[target].backlinks, p)
+				}
+			}
+			return true
+		})
+		texts[i] = text.String()
+	}
+
+	similarities := search.Similarities(texts)
+	for i := range posts {
+		p, s := &posts[i], similarities[i]
+		candidates := []int(nil)
+		for j := range posts {
+			if (j != i) && (s[j] >= minSimilarity) && ((p.series == "") || (p.series != posts[j].series)) {
+				candidates = append(candidates, j)
+			}
+		}
+		sort.SliceStable(candidates, func(x int, y int) bool {
+			return s[candidates[x]] > s[candidates[y]]
+		})
+		if len(candidates) > numRelated {
+			candidates = candidates[:numRelated]
+		}
+		for _, j := range candidates {
+			ret[p.filename].related = append(ret[p.filename].related, &posts[j])
+		}
+	}
+	return ret, nil
+}
+
+// writeCard writes a blog post's social card image and returns the metadata
+// that refers to it. The card is a thumbnail of the post's first image (or its
+// "Image: etc" metadata, if it has that) or, for a post without images, a text
+// card showing its title.
+//
+// Decoding, resizing and encoding every post's card is slow, so if what the
+// card shows hasn't changed since the last build (according to its hash in
+// cardSumsFilename) then the card image already on disk is re-used.
+func writeCard(out *outputs, p *blogPost, title string, doc *markdown.Node) (*htmlCard, error) {
+	h := sha256.New()
+	srcBytes, format := []byte(nil), "png"
+	subtitle := site.Title + " · " + p.date
+	if src := cardSource(p, doc); src != "" {
+		b, err := ioutil.ReadFile(src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: card image: %v", p.filename, err)
+		}
+		_, srcFormat, err := image.DecodeConfig(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("%s: card image: %s: %v", p.filename, src, err)
+		}
+		if srcFormat == "jpeg" {
+			// Photos are much smaller as JPEGs than as PNGs.
+			format = "jpeg"
+		}
+		srcBytes = b
+		fmt.Fprintf(h, "image %d\n", len(b))
+		h.Write(b)
+	} else {
+		fmt.Fprintf(h, "text %q %q\n", title, subtitle)
+	}
+	filename := cardFilename(p.filename, format)
+	sum := hex.EncodeToString(h.Sum(nil))
+	out.cardSums[filename] = sum
+
+	contents, err := []byte(nil), error(nil)
+	if readCardSums(out)[filename] == sum {
+		contents, err = ioutil.ReadFile(filename)
+	}
+	if contents == nil {
+		contents, err = encodeCard(srcBytes, format, title, subtitle)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: card image: %v", p.filename, err)
+	}
+	out.writeFile(filename, contents)
+
+	description := p.summary
+	if description == "" {
+		description = summarize(doc)
+	}
+	return &htmlCard{
+		Title:       title,
+		Description: description,
+		URL:         site.URL + htmlFilename(p.filename),
+		Image:       site.URL + filename,
+	}, nil
+}
+
+// encodeCard returns a card image, in the given format. It is a thumbnail of
+// srcBytes (an encoded image) or, if that is nil, a text card.
+func encodeCard(srcBytes []byte, format string, title string, subtitle string) ([]byte, error) {
+	m := image.Image(nil)
+	if srcBytes != nil {
+		srcImage, _, err := image.Decode(bytes.NewReader(srcBytes))
+		if err != nil {
+			return nil, err
+		}
+		m = card.Thumbnail(srcImage)
+	} else {
+		c, err := card.TextCard(title, subtitle)
+		if err != nil {
+			return nil, err
+		}
+		m = c
+	}
+
+	buf := &bytes.Buffer{}
+	if format == "jpeg" {
+		if err := jpeg.Encode(buf, m, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+	} else if err := png.Encode(buf, m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// cardSumsFilename holds, for each card image, a hash of what it shows: the
+// source image's contents or the text card's title and subtitle. Changing how
+// cards are drawn (the lib/card package) means deleting this file, so that
+// every card is drawn again.
+const cardSumsFilename = "blog/cards/cards.sum"
+
+// readCardSums returns the card sums on disk, reading them on first use. A
+// missing or malformed file holds no sums.
+func readCardSums(out *outputs) map[string]string {
+	if out.oldCardSums == nil {
+		out.oldCardSums = map[string]string{}
+		b, _ := ioutil.ReadFile(cardSumsFilename)
+		for _, line := range strings.Split(string(b), "\n") {
+			if fields := strings.Fields(line); len(fields) == 2 {
+				out.oldCardSums[fields[0]] = fields[1]
+			}
+		}
+	}
+	return out.oldCardSums
+}
+
+// writeCardSums writes this build's card sums, one "filename hash" per line,
+// sorted by filename.
+func writeCardSums(out *outputs) {
+	filenames := make([]string, 0, len(out.cardSums))
+	for f := range out.cardSums {
+		filenames = append(filenames, f)
+	}
+	sort.Strings(filenames)
+	buf := &bytes.Buffer{}
+	for _, f := range filenames {
+		fmt.Fprintf(buf, "%s %s\n", f, out.cardSums[f])
+	}
+	out.writeFile(cardSumsFilename, buf.Bytes())
+}
+
+// cardFilename returns the name of a blog post's card image, such as
+// "blog/cards/2022/qoir.png" for "blog/2022/qoir.md".
+func cardFilename(filename string, format string) string {
+	return "blog/cards/" + strings.TrimSuffix(strings.TrimPrefix(filename, "blog/"), ".md") + "." + format
+}
+
+// cardSource returns the file name of the image that a blog post's card
+// shows, or "" if the post has no (PNG, JPEG or GIF) images.
+func cardSource(p *blogPost, doc *markdown.Node) (src string) {
+	if p.image != "" {
+		return path.Join(path.Dir(p.filename), p.image)
+	}
+	doc.Walk(func(n *markdown.Node) bool {
+		if src != "" {
+			return false
+		} else if n.Kind != markdown.KindImage {
+			return true
+		}
+		d := n.Destination
+		if strings.Contains(d, ":") || strings.HasPrefix(d, "/") {
+			return false
+		}
+		switch path.Ext(d) {
+		case ".gif", ".jpeg", ".jpg", ".png":
+			src = path.Join(path.Dir(p.filename), d)
+		}
+		return false
+	})
+	return src
+}
+
+// tocThreshold is the number of sections and subsections above which a blog
+// post gets a table of contents.
+const tocThreshold = 5
+
+// tocHeadings returns doc's section and subsection (level 2 and 3) headings.
+func tocHeadings(doc *markdown.Node) (headings []*markdown.Node) {
+	for _, c := range doc.Children {
+		if (c.Kind == markdown.KindHeading) && (2 <= c.Level) && (c.Level <= 3) {
+			headings = append(headings, c)
+		}
+	}
+	return headings
+}
+
+// writeTOC writes a table of contents, linking to each heading, as nested
+// lists. Subsections are listed under their sections.
+func writeTOC(dst *bytes.Buffer, headings []*markdown.Node, ids map[*markdown.Node]string) {
+	dst.WriteString("<nav class=\"toc\">\n<p>Contents</p>\n<ul>\n")
+	depth := 0
+	for i, h := range headings {
+		// A list can only nest one level deeper than its parent, even if
+		// (say) a level 2 heading is followed by a level 4 heading.
+		d := h.Level - headings[0].Level
+		if d < 0 {
+			d = 0
+		} else if d > depth+1 {
+			d = depth + 1
+		}
+		if i > 0 {
+			if d > depth {
+				dst.WriteString("\n<ul>\n")
+			} else {
+				dst.WriteString("</li>\n")
+				for ; depth > d; depth-- {
+					dst.WriteString("</ul>\n</li>\n")
+				}
+			}
+		}
+		depth = d
+		fmt.Fprintf(dst, "<li><a href=\"#%s\">%s</a>",
+			template.HTMLEscapeString(ids[h]), template.HTMLEscapeString(markdown.PlainText(h)))
+	}
+	dst.WriteString("</li>\n")
+	for ; depth > 0; depth-- {
+		dst.WriteString("</ul>\n</li>\n")
+	}
+	dst.WriteString("</ul>\n</nav>\n")
+}
 
-import (
-	"bytes"
-	"errors"
-	"fmt"
-	"io/ioutil"
-	"os"
-	"sort"
-	"strings"
-)
+// highlightCode highlights the declarations in Go, C, C++ and Wuffs code
+// blocks. Most of the blog posts' code blocks don't say what language they're
+// in, so it has to guess.
+func highlightCode(dst *bytes.Buffer, lang string, code string) bool {
+	if lang != "" {
+		lang = highlight.Normalize(lang)
+	} else {
+		lang = highlight.Detect(code)
+	}
+	return highlight.Write(dst, lang, code)
+}
 
-func main() {
-	if err := main1(); err != nil {
-		os.Stderr.WriteString(err.Error() + "\n")
-		os.Exit(1)
+// loadMarkdown parses a Markdown file, splitting off its "# Title" heading
+// (which may be nil) from the rest of the document. For blog posts, it also
+// removes the trailing metadata block (and the "---" line before it). The HTML
+// template and the feed show that information separately.
+//
+// The returned nodes are cached and must not be modified.
+func loadMarkdown(out *outputs, filename string, isPost bool) (title *markdown.Node, doc *markdown.Node, err error) {
+	src, err := out.readFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c := out.markdownCache[filename]; (c != nil) && (c.isPost == isPost) && bytes.Equal(c.src, src) {
+		return c.title, c.doc, nil
+	}
+	defer func() {
+		if err == nil {
+			out.markdownCache[filename] = &cachedMarkdown{src, isPost, title, doc}
+		}
+	}()
+	doc = markdown.Parse(src)
+
+	if c := doc.Children; (len(c) > 0) && (c[0].Kind == markdown.KindHeading) && (c[0].Level == 1) {
+		title = c[0]
+		doc.Children = c[1:]
+	}
+	if c := doc.Children; isPost && (len(c) > 0) && (c[len(c)-1].Kind == markdown.KindParagraph) {
+		c = c[:len(c)-1]
+		if (len(c) > 0) && (c[len(c)-1].Kind == markdown.KindThematicBreak) {
+			c = c[:len(c)-1]
+		}
+		doc.Children = c
 	}
+	return title, doc, nil
 }
 
-func main1() error {
-	posts, err := findBlogPosts()
+// summarize returns the plain text of a blog post's first paragraph. Many
+// posts start with an "_Summary: etc_" paragraph, whose "Summary: " prefix is
+// redundant in a feed's summary element.
+func summarize(doc *markdown.Node) string {
+	for _, c := range doc.Children {
+		if c.Kind == markdown.KindParagraph {
+			s := strings.Join(strings.Fields(markdown.PlainText(c)), " ")
+			return strings.TrimPrefix(s, "Summary: ")
+		}
+	}
+	return ""
+}
+
+// absoluteURL returns a function that resolves the links and images in a
+// blog post, such as "./qoir.png", to absolute URLs such as
+// "https://nigeltao.github.io/blog/2022/qoir.png". Relative URLs don't work
+// in a feed reader.
+func absoluteURL(filename string) func(string) string {
+	base, err := url.Parse(site.URL + filename)
 	if err != nil {
-		return err
+		panic(err)
 	}
-	if len(posts) == 0 {
-		return errors.New("no blog posts")
+	return func(u string) string {
+		ref, err := url.Parse(rewriteURL(u))
+		if err != nil {
+			return u
+		}
+		return base.ResolveReference(ref).String()
+	}
+}
+
+// xmlEscape escapes the characters that are special in XML text. It also
+// drops any characters that XML 1.0 does not allow at all, such as most ASCII
+// control codes, which would otherwise make the whole feed invalid.
+func xmlEscape(s string) string {
+	return xmlEscaper.Replace(strings.Map(xmlValidRune, s))
+}
+
+func xmlValidRune(r rune) rune {
+	switch {
+	case (r == '\t') || (r == '\n') || (r == '\r'):
+		return r
+	case (r < 0x20) || (r == 0xFFFE) || (r == 0xFFFF):
+		return -1
+	case (0xD800 <= r) && (r < 0xE000):
+		return -1
+	}
+	return r
+}
+
+var xmlEscaper = strings.NewReplacer(
+	`&`, "&amp;",
+	`<`, "&lt;",
+	`>`, "&gt;",
+)
+
+// xmlAttrEscape escapes the characters that are special in XML attribute
+// values.
+func xmlAttrEscape(s string) string {
+	return strings.ReplaceAll(xmlEscaper.Replace(s), `"`, "&quot;")
+}
+
+// htmlFilename returns the name of the HTML file rendered from the given
+// Markdown file: "foo.md" becomes "foo.html" and "README.md" becomes
+// "index.html".
+func htmlFilename(filename string) string {
+	if path.Base(filename) == "README.md" {
+		return filename[:len(filename)-len("README.md")] + "index.html"
+	} else if strings.HasSuffix(filename, ".md") {
+		return filename[:len(filename)-3] + ".html"
+	}
+	return filename
+}
+
+// rewriteURL converts relative links to Markdown files, such as
+// "../2021/fastest-safest-png-decoder.md#anchor", to link to the rendered HTML
+// files instead.
+func rewriteURL(u string) string {
+	if strings.Contains(u, ":") || strings.HasPrefix(u, "//") {
+		return u
+	}
+	p, fragment := u, ""
+	if i := strings.IndexByte(u, '#'); i >= 0 {
+		p, fragment = u[:i], u[i:]
+	}
+	return htmlFilename(p) + fragment
+}
+
+// match finds substring in src, copying what precedes it to dst (unless dst is
+// nil) and returning the rest of src, starting with substring. If substring
+// isn't found, it returns src and false, without copying anything.
+func match(dst *bytes.Buffer, src []byte, substring string) (rest []byte, ok bool) {
+	i := bytes.Index(src, []byte(substring))
+	if i < 0 {
+		return src, false
 	}
-	if err := writeFeed(posts); err != nil {
+	if dst != nil {
+		dst.Write(src[:i])
+	}
+	return src[i:], true
+}
+
+// checkLinks checks that every relative link and image in the Markdown files
+// points to a file that exists and, if it has a "#fragment", that the fragment
+// matches a heading in that file. It also checks that every asset (such as an
+// image) under ./blog is used, either by a Markdown or hand-written HTML file
+// or by a program (such as a .go file) that generates or consumes it.
+//
+// Each problem is printed as a "filename:line: message" line.
+func checkLinks() error {
+	generated, err := generatedFiles()
+	if err != nil {
 		return err
 	}
-	if err := writeReadme(posts); err != nil {
+	sources, err := findSiteFiles(generated)
+	if err != nil {
 		return err
 	}
+
+	problems := 0
+	report := func(filename string, line int, format string, args ...interface{}) {
+		if line > 0 {
+			filename = filename + ":" + strconv.Itoa(line)
+		}
+		fmt.Printf("%s: %s\n", filename, fmt.Sprintf(format, args...))
+		problems++
+	}
+
+	used := map[string]bool{}
+	anchors := map[string]map[string]bool{}
+	for _, filename := range sources.markdown {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		doc := markdown.Parse(src)
+		anchors[filename] = headingIDSet(doc)
+
+		var links []*markdown.Node
+		doc.Walk(func(n *markdown.Node) bool {
+			if (n.Kind == markdown.KindLink) || (n.Kind == markdown.KindImage) {
+				links = append(links, n)
+			}
+			return true
+		})
+
+		for _, n := range links {
+			target, fragment, ok := resolveLink(filename, n.Destination)
+			if !ok {
+				continue
+			}
+			used[target] = true
+			if _, err := os.Stat(target); err != nil {
+				report(filename, n.Line, "broken link %q", n.Destination)
+				continue
+			}
+			if (fragment == "") || !strings.HasSuffix(target, ".md") {
+				continue
+			}
+			if anchors[target] == nil {
+				tsrc, err := ioutil.ReadFile(target)
+				if err != nil {
+					return err
+				}
+				anchors[target] = headingIDSet(markdown.Parse(tsrc))
+			}
+			if !anchors[target][fragment] {
+				report(filename, n.Line, "broken anchor %q", n.Destination)
+			}
+		}
+	}
+
+	for _, filename := range sources.html {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		for _, m := range htmlRefRegexp.FindAllStringSubmatch(string(src), -1) {
+			if target, _, ok := resolveLink(filename, m[1]); ok {
+				used[target] = true
+			}
+		}
+	}
+
+	for _, filename := range sources.programs {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		dir := path.Dir(filename)
+		for _, m := range programRefRegexp.FindAllString(string(src), -1) {
+			pattern := path.Join(dir, printfVerbRegexp.ReplaceAllString(m, "*"))
+			for _, asset := range sources.assets {
+				if ok, _ := path.Match(pattern, asset); ok {
+					used[asset] = true
+				}
+			}
+		}
+	}
+
+	for _, asset := range site.KeptAssets {
+		used[asset] = true
+	}
+	for _, asset := range sources.assets {
+		if !used[asset] {
+			report(asset, 0, "unused asset")
+		}
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("checklinks: found %d problem(s)", problems)
+	}
 	return nil
 }
 
-func writeFeed(posts []blogPost) error {
-	dst := bytes.NewBuffer(nil)
-	dst.WriteString(`<feed xmlns="http://www.w3.org/2005/Atom">` + "\n")
-	dst.WriteString(`  <link href="https://nigeltao.github.io/feed.xml" ` +
-		`rel="self" type="application/atom+xml"/>` + "\n")
-	dst.WriteString(`  <link href="https://nigeltao.github.io/" ` +
-		`rel="alternate" type="text/html"/>` + "\n")
-	fmt.Fprintf(dst, `  <updated>%sT00:00:00+00:00</updated>`+"\n", posts[len(posts)-1].date)
-	dst.WriteString(`  <id>https://nigeltao.github.io/feed.xml</id>` + "\n")
-	dst.WriteString(`  <title type="html">Nigel Tao's blog</title>` + "\n")
+// siteFiles are the hand-written (not generated) files in the repository,
+// categorized by what checkLinks does with them.
+type siteFiles struct {
+	markdown []string
+	html     []string
+	programs []string
+	assets   []string
+}
 
-	for i := len(posts) - 1; i >= 0; i-- {
-		if err := writeFeed1(dst, &posts[i]); err != nil {
+// findSiteFiles finds README.md and the files under ./blog, skipping the
+// generated files: those in the generated set and everything in the
+// generatedDirs.
+func findSiteFiles(generated map[string]bool) (ret siteFiles, _ error) {
+	ret.markdown = append(ret.markdown, "README.md")
+	err := filepath.Walk("blog", func(filename string, info os.FileInfo, err error) error {
+		if err != nil {
 			return err
 		}
-	}
+		filename = filepath.ToSlash(filename)
+		if info.IsDir() {
+			for _, dir := range generatedDirs {
+				if filename == dir {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		} else if generated[filename] {
+			return nil
+		}
+		switch ext := path.Ext(filename); ext {
+		case ".md":
+			ret.markdown = append(ret.markdown, filename)
+		case ".html":
+			md := filename[:len(filename)-len(ext)] + ".md"
+			if _, err := os.Stat(md); os.IsNotExist(err) {
+				ret.html = append(ret.html, filename)
+			}
+		case ".c", ".cc", ".go", ".h":
+			ret.programs = append(ret.programs, filename)
+		default:
+			if !strings.HasPrefix(path.Base(filename), "README.") {
+				ret.assets = append(ret.assets, filename)
+			}
+		}
+		return nil
+	})
+	return ret, err
+}
 
-	dst.WriteString("</feed>\n")
-	return ioutil.WriteFile("feed.xml", dst.Bytes(), 0666)
+// generatedFiles returns the set of files that update.go generates, by
+// building the site (in memory).
+func generatedFiles() (map[string]bool, error) {
+	out := newOutputs(nil)
+	if err := build(out); err != nil {
+		return nil, err
+	}
+	ret := map[string]bool{}
+	for _, filename := range out.filenames {
+		ret[filename] = true
+	}
+	return ret, nil
 }
 
-func writeFeed1(dst *bytes.Buffer, p *blogPost) error {
-	filename := p.filename
-	if strings.HasSuffix(filename, ".md") {
-		filename = filename[:len(filename)-3] + ".html"
+// writeEPUB writes an EPUB e-book of a series, or of the blog posts named on
+// the command line, to filename. Each post is a chapter. Images are embedded
+// in the book. Links to other posts in the book point to their chapters and
+// other relative links point to the web site.
+func writeEPUB(filename string) error {
+	posts, err := findBlogPosts()
+	if err != nil {
+		return err
+	}
+	book := &epub.Book{
+		Author:     site.Author,
+		Language:   site.Language,
+		Stylesheet: epubStylesheet,
 	}
 
-	dst.WriteString("  <entry>\n")
-	fmt.Fprintf(dst, `    <title type="html">%s</title>`+"\n", p.title)
-	fmt.Fprintf(dst, `    <link href="https://nigeltao.github.io/%s" `+
-		`rel="alternate" type="text/html" title="%s"/>`+"\n", filename, p.title)
-	fmt.Fprintf(dst, `    <published>%sT00:00:00+00:00</published>`+"\n", p.date)
-	fmt.Fprintf(dst, `    <updated>%sT00:00:00+00:00</updated>`+"\n", p.date)
-	fmt.Fprintf(dst, `    <id>https://nigeltao.github.io/%s</id>`+"\n", filename)
-	dst.WriteString("  </entry>\n")
-	return nil
+	selected := []*blogPost(nil)
+	if *seriesFlag != "" {
+		if flag.NArg() > 0 {
+			return errors.New("-epub: use either -series or a list of posts, not both")
+		}
+		allSeries, err := findSeries(posts)
+		if err != nil {
+			return err
+		}
+		for _, ser := range allSeries {
+			if (ser.title == *seriesFlag) || (ser.slug == *seriesFlag) {
+				selected = ser.parts
+				book.Title = ser.title
+				book.Identifier = site.URL + htmlFilename(ser.filename())
+			}
+		}
+		if selected == nil {
+			return fmt.Errorf("-epub: no series named %q", *seriesFlag)
+		}
+	} else if flag.NArg() > 0 {
+		for _, arg := range flag.Args() {
+			arg = path.Clean(filepath.ToSlash(arg))
+			found := false
+			for i := range posts {
+				if posts[i].filename == arg {
+					selected = append(selected, &posts[i])
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("-epub: %s is not a published blog post", arg)
+			}
+		}
+		if len(selected) == 1 {
+			book.Title = markdown.PlainText(markdown.ParseInline(selected[0].title))
+			book.Identifier = site.URL + htmlFilename(selected[0].filename)
+		} else {
+			book.Title = site.Title
+			names := []string(nil)
+			for _, p := range selected {
+				names = append(names, htmlFilename(p.filename))
+			}
+			book.Identifier = site.URL + "#" + strings.Join(names, ",")
+		}
+	} else {
+		return errors.New("-epub: use -series or list the blog posts to include")
+	}
+
+	chapters := map[string]string{}
+	for i, p := range selected {
+		chapters[p.filename] = epub.ChapterFilename(i+1, len(selected))
+		if t, err := time.Parse("2006-01-02", p.lastModified()); err != nil {
+			return err
+		} else if book.Modified.Before(t) {
+			book.Modified = t
+		}
+	}
+
+	book.Resources = append(book.Resources, &epub.Resource{
+		Filename:  "fonts/go-mono.ttf",
+		MediaType: "font/ttf",
+		Data:      gomono.TTF,
+	})
+	images := map[string]string{}
+	out := newOutputs(nil)
+	for _, p := range selected {
+		title, doc, err := loadMarkdown(out, p.filename, true)
+		if err != nil {
+			return err
+		}
+		var rewriteErr error
+		absolute := absoluteURL(p.filename)
+		rewrite := func(u string) string {
+			target, fragment, ok := resolveLink(p.filename, u)
+			if !ok {
+				return u
+			} else if (target == p.filename) && strings.HasPrefix(u, "#") {
+				return u
+			} else if c := chapters[target]; c != "" {
+				if fragment != "" {
+					return c + "#" + fragment
+				}
+				return c
+			}
+			mediaType := epub.MediaType(path.Ext(target))
+			if !strings.HasPrefix(mediaType, "image/") {
+				return absolute(u)
+			}
+			if images[target] == "" {
+				data, err := ioutil.ReadFile(target)
+				if err != nil {
+					rewriteErr = fmt.Errorf("%s: %v", p.filename, err)
+					return u
+				}
+				images[target] = "images/" + strings.TrimPrefix(target, "blog/")
+				book.Resources = append(book.Resources, &epub.Resource{
+					Filename:  images[target],
+					MediaType: mediaType,
+					Data:      data,
+				})
+			}
+			return images[target]
+		}
+
+		ids := headingIDs(title, doc)
+		r := &markdown.Renderer{
+			RewriteURL: rewrite,
+			HeadingIDs: ids,
+			XHTML:      true,
+		}
+		chapter := &epub.Chapter{
+			Filename: chapters[p.filename],
+			Title:    p.title,
+		}
+		body := &bytes.Buffer{}
+		if title != nil {
+			chapter.Title = markdown.PlainText(title)
+			body.WriteString(`<h1 id="` + template.HTMLEscapeString(ids[title]) + `">`)
+			r.RenderChildren(body, title)
+			body.WriteString("</h1>\n")
+		}
+		r.Render(body, doc)
+		fmt.Fprintf(body, "<p class=\"published\">Published: %s", p.date)
+		if p.updated != "" {
+			fmt.Fprintf(body, "<br />\nUpdated: %s", p.updated)
+		}
+		body.WriteString("</p>\n")
+		if rewriteErr != nil {
+			return rewriteErr
+		}
+		chapter.Body = body.Bytes()
+		for _, h := range tocHeadings(doc) {
+			chapter.Headings = append(chapter.Headings, epub.Heading{
+				Level: h.Level,
+				ID:    ids[h],
+				Title: markdown.PlainText(h),
+			})
+		}
+		book.Chapters = append(book.Chapters, chapter)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := book.Write(buf); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
 }
 
-func writeReadme(posts []blogPost) error {
-	dst := bytes.NewBuffer(nil)
+// epubStylesheet styles the EPUB e-books. E-readers usually have their own
+// preferences for body text, but not necessarily a good monospace font, so
+// code uses the embedded Go Mono font.
+const epubStylesheet = `@font-face { font-family: "Go Mono"; src: url(fonts/go-mono.ttf) }
+blockquote { border-left: 0.25em solid #ccc; margin-left: 0; padding-left: 1em }
+code, pre { font-family: "Go Mono", monospace; font-size: 0.85em }
+img { max-width: 100% }
+pre { white-space: pre-wrap }
+table { border-collapse: collapse }
+td, th { border: 1px solid #ccc; padding: 0.2em 0.5em }
+.published { color: #555; font-size: 0.9em }
+`
 
-	src, err := ioutil.ReadFile("README.md")
+// lint explains, for each Markdown file under ./blog, whether it's a blog post
+// and, if not, why not. A typo in a post's metadata (its final paragraph) can
+// otherwise make it silently disappear from README.md and the feeds.
+//
+// It also reports likely mistakes: invalid metadata, images without alt text,
+// posts with the same title and headings containing non-ASCII characters that
+// look like ASCII ones (such as a Cyrillic "а" instead of a Latin "a"), which
+// break searching and their "#fragment" links.
+func lint() error {
+	// Skip the Markdown files that update.go itself writes, such as the
+	// per-year archive pages. If the build fails then those files can't be
+	// told apart from hand-written ones, so the files that aren't blog posts
+	// go unmentioned. The build failure is reported once, below, unless a
+	// post that fails to load (which is what usually breaks the build)
+	// already explains it.
+	generated, buildErr := generatedFiles()
+	sources, err := findSiteFiles(generated)
 	if err != nil {
 		return err
 	}
+	loadFailed := false
 
-	const blog = "\n\n## Blog\n\n"
-	src = match(dst, src, blog)
+	problems := 0
+	report := func(filename string, line int, format string, args ...interface{}) {
+		if line > 0 {
+			filename = filename + ":" + strconv.Itoa(line)
+		}
+		fmt.Printf("%s: %s\n", filename, fmt.Sprintf(format, args...))
+		problems++
+	}
 
-	dst.WriteString(blog)
-	dst.WriteString("[RSS/Atom feed](/feed.xml).\n\n")
-	for _, p := range posts {
-		fmt.Fprintf(dst, "- %s [%s](./%s)\n", p.date, p.title, p.filename)
+	titles := map[string]string{}
+	for _, filename := range sources.markdown {
+		if !strings.HasPrefix(filename, "blog/") {
+			continue
+		}
+		if strings.Count(filename, "/") != 2 {
+			if buildErr == nil {
+				fmt.Printf("%s: not a blog post: it isn't in a blog/* directory\n", filename)
+			}
+			continue
+		}
+		post, err := load(filename)
+		if errors.Is(err, errMisspeltPublished) {
+			report(filename, 0, "%v", err)
+			continue
+		} else if errors.Is(err, errNotABlogPost) {
+			if buildErr == nil {
+				fmt.Printf("%s: %v\n", filename, err)
+			}
+			continue
+		} else if err != nil {
+			fmt.Println(err)
+			problems++
+			loadFailed = true
+			continue
+		}
+
+		switch {
+		case post.draft:
+			fmt.Printf("%s: draft (included with -drafts)\n", filename)
+		case post.date > *nowFlag:
+			fmt.Printf("%s: scheduled for %s (held back until then)\n", filename, post.date)
+		default:
+			fmt.Printf("%s: blog post, published %s\n", filename, post.date)
+		}
+		if other := titles[post.title]; other != "" {
+			report(filename, 1, "duplicate title %q (also used by %s)", post.title, other)
+		} else {
+			titles[post.title] = filename
+		}
+
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		markdown.Parse(src).Walk(func(n *markdown.Node) bool {
+			switch n.Kind {
+			case markdown.KindHeading:
+				for _, r := range markdown.PlainText(n) {
+					if ascii, ok := lookAlikes[r]; ok {
+						report(filename, n.Line, "heading contains U+%04X, which looks like %q", r, ascii)
+					}
+				}
+				return false
+			case markdown.KindImage:
+				if strings.TrimSpace(markdown.PlainText(n)) == "" {
+					report(filename, n.Line, "image %q has no alt text", n.Destination)
+				}
+				return false
+			}
+			return true
+		})
 	}
 
-	const proj = "\n\n## Projects\n\n"
-	src = match(nil, src, proj)
+	if (buildErr != nil) && !loadFailed {
+		fmt.Printf("build failed: %v\n", buildErr)
+		problems++
+	}
+	if problems > 0 {
+		return fmt.Errorf("lint: found %d problem(s)", problems)
+	}
+	return nil
+}
 
-	const copr = "\n\nCopyright "
-	src = match(dst, src, copr)
-	fmt.Fprintf(dst, "\n\nCopyright %s-%s Nigel Tao\n",
-		posts[0].date[:4],
-		posts[len(posts)-1].date[:4],
-	)
+// lookAlikes maps non-ASCII characters that are easily mistaken for ASCII
+// ones (or for an ASCII space) to the ASCII characters they look like. It
+// deliberately doesn't cover all of Unicode's confusables. For example, the
+// dotless "ı" in blog/2020/miileeniol.md is intentional.
+var lookAlikes = map[rune]string{}
+
+func init() {
+	for _, pair := range [][2]string{
+		// Cyrillic.
+		{"АВЕКМНОРСТХ", "ABEKMHOPCTX"},
+		{"аеорсухіјѕһԁ", "aeopcyxijshd"},
+		// Greek.
+		{"ΑΒΕΖΗΙΚΜΝΟΡΤΥΧ", "ABEZHIKMNOPTYX"},
+		{"ο", "o"},
+		// Spaces, invisible characters and dashes.
+		{"\u00a0\u2002\u2003\u2009\u200a\u202f", "      "},
+		{"\u200b\u200c\u200d\u2060\ufeff", "\x00\x00\x00\x00\x00"},
+		{"\u2010\u2011\u2212", "---"},
+	} {
+		ascii := []rune(pair[1])
+		for i, r := range []rune(pair[0]) {
+			if ascii[i] == 0 {
+				lookAlikes[r] = ""
+			} else {
+				lookAlikes[r] = string(ascii[i])
+			}
+		}
+	}
+	// Fullwidth forms, such as "Ａ", look like their ASCII equivalents.
+	for r := rune(0xFF01); r <= 0xFF5E; r++ {
+		lookAlikes[r] = string(r - 0xFF01 + '!')
+	}
+}
 
-	return ioutil.WriteFile("README.md", dst.Bytes(), 0666)
+// resolveLink resolves a link or image destination, relative to the file
+// that contains it, to a filename (relative to the repository root) and
+// fragment. It returns false for external (absolute) URLs.
+func resolveLink(from string, dest string) (filename string, fragment string, ok bool) {
+	if strings.Contains(dest, ":") || strings.HasPrefix(dest, "//") {
+		return "", "", false
+	}
+	if i := strings.IndexByte(dest, '#'); i >= 0 {
+		dest, fragment = dest[:i], dest[i+1:]
+	}
+	if i := strings.IndexByte(dest, '?'); i >= 0 {
+		dest = dest[:i]
+	}
+	if dest, err := url.PathUnescape(dest); err == nil {
+		if dest == "" {
+			filename = from
+		} else if strings.HasPrefix(dest, "/") {
+			filename = path.Clean(dest[1:])
+		} else {
+			filename = path.Join(path.Dir(from), dest)
+		}
+		return filename, fragment, true
+	}
+	return "", "", false
 }
 
-func match(dst *bytes.Buffer, src []byte, substring string) []byte {
-	i := bytes.Index(src, []byte(substring))
-	if i < 0 {
-		i = len(src)
+// headingIDs returns the anchor IDs of a Markdown file's headings. The title
+// is passed separately, as loadMarkdown splits it off, but it still affects
+// the IDs: a later heading with the same text as the title gets a suffix.
+func headingIDs(title *markdown.Node, doc *markdown.Node) map[*markdown.Node]string {
+	if title == nil {
+		return markdown.HeadingIDs(doc)
 	}
-	if dst != nil {
-		dst.Write(src[:i])
+	full := &markdown.Node{Kind: markdown.KindDocument}
+	full.Children = append([]*markdown.Node{title}, doc.Children...)
+	return markdown.HeadingIDs(full)
+}
+
+func headingIDSet(doc *markdown.Node) map[string]bool {
+	ret := map[string]bool{}
+	for _, id := range markdown.HeadingIDs(doc) {
+		ret[id] = true
 	}
-	return src[i:]
+	return ret
 }
 
+// htmlRefRegexp matches the src and href attributes in hand-written HTML.
+var htmlRefRegexp = regexp.MustCompile(`(?:src|href)="([^"]*)"`)
+
+// programRefRegexp matches file names mentioned in programs' source code,
+// such as "qoir.png" or "gamma-aware-curve-%d.png". printfVerbRegexp matches
+// the "%d" in the latter, so that it can be converted to a "*" glob.
+var (
+	programRefRegexp = regexp.MustCompile(`[\w%?*./-]+\.[A-Za-z]+`)
+	printfVerbRegexp = regexp.MustCompile(`%[0-9]*d`)
+)
+
 type blogPost struct {
 	date     string
 	filename string
 	title    string
+
+	// The fields below are optional. They come from the post's metadata
+	// block, described in the load function's comment. The series and part
+	// can also come from the title, such as "Foo Bar Part 3: Baz". The
+	// updated date can also come from inline "_Update on 2000-00-00: etc_"
+	// notes. It is empty if the post hasn't been revised since it was
+	// published.
+	updated string
+	tags    []string
+	series  string
+	part    int
+	summary string
+	draft   bool
+	image   string
 }
 
+// findBlogPosts returns the published blog posts, oldest first. Drafts (unless
+// the -drafts flag is given) and posts dated after the -now date are skipped.
 func findBlogPosts() (posts []blogPost, _ error) {
 	infos0, err := ioutil.ReadDir("./blog")
 	if err != nil {
@@ -153,10 +2501,12 @@ func findBlogPosts() (posts []blogPost, _ error) {
 			}
 			filename := "blog/" + f0.Name() + "/" + f1.Name()
 			post, err := load(filename)
-			if err == errNotABlogPost {
+			if errors.Is(err, errNotABlogPost) {
 				continue
 			} else if err != nil {
 				return nil, err
+			} else if (post.draft && !*draftsFlag) || (post.date > *nowFlag) {
+				continue
 			}
 			posts = append(posts, post)
 		}
@@ -171,6 +2521,22 @@ func findBlogPosts() (posts []blogPost, _ error) {
 	return posts, nil
 }
 
+// load loads a blog post's title and metadata. A blog post's first line is
+// "# Title" and its final paragraph is a metadata block of "Key: value" lines,
+// such as:
+//
+//	Published: 2022-05-11
+//	Updated: 2022-06-01
+//	Tags: compression, zstandard
+//	Series: Zstandard Worked Example
+//	Part: 1
+//	Summary: A one sentence summary, for the feed.
+//	Draft: true
+//	Image: ./hero.png
+//
+// Only the Published line is required. A Markdown file that doesn't look like
+// this is not a blog post and load returns an error that wraps errNotABlogPost,
+// saying why.
 func load(filename string) (blogPost, error) {
 	b, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -179,37 +2545,185 @@ func load(filename string) (blogPost, error) {
 	for (len(b) > 0) && (b[len(b)-1] == '\n') {
 		b = b[:len(b)-1]
 	}
+	src := b
 
 	if (len(b) < 2) || (b[0] != '#') || (b[1] != ' ') {
-		return blogPost{}, errNotABlogPost
+		return blogPost{}, fmt.Errorf("%w: the first line isn't a \"# Title\"", errNotABlogPost)
 	}
 	b = b[2:]
 
 	title := ""
 	if i := bytes.IndexByte(b, '\n'); i < 0 {
-		return blogPost{}, errNotABlogPost
+		return blogPost{}, fmt.Errorf("%w: there's nothing after the title", errNotABlogPost)
 	} else {
 		title = string(b[:i])
 		b = b[i+1:]
 	}
 
-	const suffix0, suffix1 = "Published: ", "2000-00-00"
-	if n := len(suffix0) + len(suffix1); len(b) <= n {
-		return blogPost{}, errNotABlogPost
+	// The final paragraph is the metadata block.
+	if i := bytes.LastIndex(b, []byte("\n\n")); i < 0 {
+		return blogPost{}, fmt.Errorf("%w: there's no final (metadata) paragraph", errNotABlogPost)
 	} else {
-		b = b[len(b)-n:]
+		b = b[i+2:]
 	}
-	if n := len(suffix0); string(b[:n]) != suffix0 {
-		return blogPost{}, errNotABlogPost
-	} else {
-		b = b[n:]
+	if !bytes.HasPrefix(b, []byte("Published: ")) &&
+		!bytes.Contains(b, []byte("\nPublished: ")) {
+		if key := misspeltPublished(string(b)); key != "" {
+			return blogPost{}, fmt.Errorf("%w (is %q a typo?)", errMisspeltPublished, key)
+		}
+		return blogPost{}, fmt.Errorf("%w: the final paragraph has no \"Published: \" line", errNotABlogPost)
 	}
 
-	return blogPost{
-		date:     string(b),
+	post := blogPost{
 		filename: filename,
 		title:    title,
-	}, nil
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		i := strings.Index(line, ": ")
+		if i < 0 {
+			return blogPost{}, fmt.Errorf("%s: invalid metadata line %q", filename, line)
+		}
+		key, value := line[:i], strings.TrimSpace(line[i+2:])
+		switch key {
+		case "Published":
+			post.date = value
+		case "Updated":
+			post.updated = value
+		case "Tags":
+			for _, tag := range strings.Split(value, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					post.tags = append(post.tags, tag)
+				}
+			}
+		case "Series":
+			post.series = value
+		case "Part":
+			if post.part, err = strconv.Atoi(value); (err != nil) || (post.part <= 0) {
+				return blogPost{}, fmt.Errorf("%s: invalid Part %q", filename, value)
+			}
+		case "Summary":
+			post.summary = value
+		case "Draft":
+			if post.draft, err = strconv.ParseBool(value); err != nil {
+				return blogPost{}, fmt.Errorf("%s: invalid Draft %q", filename, value)
+			}
+		case "Image":
+			post.image = value
+		default:
+			return blogPost{}, fmt.Errorf("%s: unknown metadata key %q", filename, key)
+		}
+	}
+
+	for _, date := range []string{post.date, post.updated} {
+		if (date != "") && !isDate(date) {
+			return blogPost{}, fmt.Errorf("%s: invalid date %q", filename, date)
+		}
+	}
+
+	// Revisions are often noted inline, as "_Update on 2021-02-26: etc_".
+	for _, m := range updateNoteRegexp.FindAllSubmatch(src, -1) {
+		if date := string(m[1]); date > post.updated {
+			post.updated = date
+		}
+	}
+	if post.updated <= post.date {
+		post.updated = ""
+	}
+	if post.series == "" {
+		if m := seriesTitleRegexp.FindStringSubmatch(post.title); m != nil {
+			post.series = m[1]
+			post.part, _ = strconv.Atoi(m[2])
+		}
+	}
+	if (post.series == "") != (post.part == 0) {
+		return blogPost{}, fmt.Errorf("%s: Series and Part must be used together", filename)
+	}
+	return post, nil
+}
+
+// lastModified returns the post's updated date, if it has one, or its
+// published date otherwise.
+func (p *blogPost) lastModified() string {
+	if p.updated != "" {
+		return p.updated
+	}
+	return p.date
+}
+
+// partTitle returns the title to use when listing the post as part of a
+// series: "Part 3: Bitstreams" instead of "Zstandard Worked Example Part 3:
+// Bitstreams".
+func (p *blogPost) partTitle() string {
+	if m := seriesTitleRegexp.FindStringSubmatch(p.title); m != nil {
+		return "Part " + m[2] + ": " + m[3]
+	}
+	return p.title
+}
+
+// isDate returns whether s is a real calendar date in "2000-01-31" form.
+// "2022-02-30" looks like a date but isn't one.
+func isDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+// misspeltPublished returns the key of the first metadata line that looks like
+// a mistyped "Published: " key, such as "published: " or "Pubished: " or
+// "Published:" without a space, or "" if there is no such line.
+func misspeltPublished(metadata string) string {
+	for _, line := range strings.Split(metadata, "\n") {
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		if key := strings.ToLower(strings.TrimSpace(line[:i])); editDistance(key, "published") <= 2 {
+			return line[:i+1]
+		}
+	}
+	return ""
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if curr[j] > prev[j]+1 {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j] > curr[j-1]+1 {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
 }
 
 var errNotABlogPost = errors.New("not a blog post")
+
+// errMisspeltPublished is wrapped by load's error for a Markdown file that
+// would be a blog post if not for a typo in its "Published: " key. It wraps
+// errNotABlogPost, so the build skips such files, but lint counts them as
+// problems.
+var errMisspeltPublished = fmt.Errorf("%w: the final paragraph has no \"Published: \" line", errNotABlogPost)
+
+// seriesTitleRegexp matches titles like "Zstandard Worked Example Part 3:
+// Bitstreams". Posts with such titles don't need explicit Series and Part
+// metadata.
+var seriesTitleRegexp = regexp.MustCompile(`^(.+) Part (\d+): (.+)$`)
+
+// updateNoteRegexp matches inline notes like "_Update on 2021-02-26: etc_" or
+// "(_the following snippet was updated on 2020-05-05_)". The note can wrap
+// across lines.
+var updateNoteRegexp = regexp.MustCompile(`(?i)updated?\s+on\s+(\d{4}-\d{2}-\d{2})\b`)